@@ -0,0 +1,339 @@
+package gserv
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.oneofone.dev/oerrs"
+)
+
+// ErrBindTarget is returned by BindAll when out isn't a non-nil pointer to a struct.
+const ErrBindTarget = oerrs.String("BindAll needs a non-nil pointer to a struct")
+
+// bindSources are the struct tags BindAll reads values for, in order of precedence if a field has several.
+var bindSources = [...]string{"path", "query", "header", "cookie", "form"}
+
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	durationType        = reflect.TypeFor[time.Duration]()
+	fileHeaderType      = reflect.TypeFor[*multipart.FileHeader]()
+	fileHeadersType     = reflect.TypeFor[[]*multipart.FileHeader]()
+)
+
+// BindAll decodes the request's body like Bind, unless it's empty or a form, then sets the fields of out,
+// a pointer to a struct, tagged with where their values come from, ex:
+//
+//	type ListReq struct {
+//		Org    int64                 `path:"org"`
+//		Page   int                   `query:"page" default:"1"`
+//		Tags   []string              `query:"tag"`
+//		Wait   time.Duration         `query:"wait" default:"5s"`
+//		Tenant string                `header:"X-Tenant"`
+//		SID    string                `cookie:"sid"`
+//		Name   string                `form:"name"`
+//		Avatar *multipart.FileHeader `form:"avatar"`
+//	}
+//
+// Values are converted to the field's type: strings, bools, ints, uints, floats, time.Duration, encoding.TextUnmarshaler
+// (ex: time.Time as RFC 3339), pointers to them and slices of them from repeated or comma separated values.
+// The default tag is used when the request doesn't have the value and the field is still zero, form values include
+// the query like http.Request.Form, and *multipart.FileHeader or []*multipart.FileHeader form fields get the uploaded files.
+// Fields of embedded structs are bound as well.
+//
+// Every value that can't be converted is returned in a FieldErrors with status 400,
+// then out is validated once all its fields are set, see Validate.
+func (ctx *Context) BindAll(out any) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return ErrBindTarget
+	}
+
+	if ctx.hasBody() && !isFormRequest(ctx.Req) {
+		if err := ctx.decodeBody(out); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+
+	if err := ctx.bindValues(v.Elem()); err != nil {
+		return err
+	}
+	return Validate(out)
+}
+
+// bindValues sets the tagged fields of v, a struct or a pointer to one which is allocated if needed.
+func (ctx *Context) bindValues(v reflect.Value) error {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ErrBindTarget
+	}
+
+	var errs FieldErrors
+	for _, f := range bindFieldsOf(v.Type()) {
+		fv := v.FieldByIndex(f.index)
+
+		if f.files {
+			if err := ctx.bindFiles(fv, f.name); err != nil {
+				errs = append(errs, Error{Code: http.StatusBadRequest, Field: f.name, In: f.in, Message: err.Error()})
+			}
+			continue
+		}
+
+		vals, err := ctx.bindSource(f.in, f.name)
+		if err != nil {
+			errs = append(errs, Error{Code: http.StatusBadRequest, Field: f.name, In: f.in, Message: err.Error()})
+			continue
+		}
+		if len(vals) == 0 {
+			if f.def == "" || !fv.IsZero() {
+				continue
+			}
+			vals = []string{f.def}
+		}
+
+		if err := setValue(fv, vals); err != nil {
+			errs = append(errs, Error{Code: http.StatusBadRequest, Field: f.name, In: f.in, Message: err.Error()})
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// bindSource returns the values of name from in, one of bindSources.
+func (ctx *Context) bindSource(in, name string) ([]string, error) {
+	switch in {
+	case "path":
+		if v := ctx.Params.Get(name); v != "" {
+			return []string{v}, nil
+		}
+	case "query":
+		return ctx.ReqQuery[name], nil
+	case "header":
+		return ctx.Req.Header.Values(name), nil
+	case "cookie":
+		if c, err := ctx.Req.Cookie(name); err == nil {
+			return []string{c.Value}, nil
+		}
+	case "form":
+		if err := ctx.parseForm(); err != nil {
+			return nil, err
+		}
+		return ctx.Req.Form[name], nil
+	}
+	return nil, nil
+}
+
+func (ctx *Context) bindFiles(v reflect.Value, name string) error {
+	if err := ctx.parseForm(); err != nil {
+		return err
+	}
+	mf := ctx.Req.MultipartForm
+	if mf == nil || len(mf.File[name]) == 0 {
+		return nil
+	}
+	if v.Type() == fileHeaderType {
+		v.Set(reflect.ValueOf(mf.File[name][0]))
+	} else {
+		v.Set(reflect.ValueOf(mf.File[name]))
+	}
+	return nil
+}
+
+// parseForm parses the request's form once, including multipart forms.
+func (ctx *Context) parseForm() error {
+	req := ctx.Req
+	if req.Form != nil {
+		return nil
+	}
+	if ct, _, _ := mime.ParseMediaType(req.Header.Get(contentTypeHeader)); ct == "multipart/form-data" {
+		return req.ParseMultipartForm(32 << 20) // same as http.Request.FormValue
+	}
+	return req.ParseForm()
+}
+
+func (ctx *Context) hasBody() bool {
+	return ctx.Req.Body != nil && ctx.Req.Body != http.NoBody && ctx.Req.ContentLength != 0
+}
+
+func isFormRequest(req *http.Request) bool {
+	ct, _, _ := mime.ParseMediaType(req.Header.Get(contentTypeHeader))
+	return ct == "application/x-www-form-urlencoded" || ct == "multipart/form-data"
+}
+
+type bindField struct {
+	index []int
+	typ   reflect.Type
+	in    string
+	name  string
+	def   string
+	files bool
+
+	validate string // the validate tag, used to document the field
+}
+
+var bindFieldsCache sync.Map // reflect.Type -> []bindField
+
+// bindFieldsOf returns the tagged fields of the struct type t, including the ones of embedded structs.
+func bindFieldsOf(t reflect.Type) []bindField {
+	if fs, ok := bindFieldsCache.Load(t); ok {
+		return fs.([]bindField)
+	}
+	var fs []bindField
+	collectBindFields(t, nil, &fs)
+	bindFieldsCache.Store(t, fs)
+	return fs
+}
+
+func collectBindFields(t reflect.Type, index []int, fs *[]bindField) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		idx := append(slices.Clip(index), i)
+
+		in, name, ok := bindTag(f)
+		if !ok {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				collectBindFields(f.Type, idx, fs)
+			}
+			continue
+		}
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+
+		*fs = append(*fs, bindField{
+			index: idx,
+			typ:   f.Type,
+			in:    in,
+			name:  name,
+			def:   f.Tag.Get("default"),
+			files: in == "form" && (f.Type == fileHeaderType || f.Type == fileHeadersType),
+
+			validate: f.Tag.Get("validate"),
+		})
+	}
+}
+
+// bindTag returns the first of bindSources f is tagged with and the name of its value, the field name if the tag is empty.
+func bindTag(f reflect.StructField) (in, name string, ok bool) {
+	for _, in := range bindSources {
+		if name, ok := f.Tag.Lookup(in); ok {
+			if name == "" {
+				name = f.Name
+			}
+			return in, name, true
+		}
+	}
+	return "", "", false
+}
+
+// setValue sets v from vals, slices get all of them split on commas, other types the first one.
+func setValue(v reflect.Value, vals []string) error {
+	t := v.Type()
+	if t.Kind() != reflect.Slice || t.Elem().Kind() == reflect.Uint8 || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return setScalar(v, vals[0])
+	}
+
+	parts := make([]string, 0, len(vals))
+	for _, val := range vals {
+		for p := range strings.SplitSeq(val, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				parts = append(parts, p)
+			}
+		}
+	}
+
+	s := reflect.MakeSlice(t, len(parts), len(parts))
+	for i, p := range parts {
+		if err := setScalar(s.Index(i), p); err != nil {
+			return err
+		}
+	}
+	v.Set(s)
+	return nil
+}
+
+func setScalar(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		if err := setScalar(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	if tu, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := tu.UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("invalid value %q: %w", s, err)
+		}
+		return nil
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	var err error
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(s); err == nil {
+			v.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if n, err = strconv.ParseInt(s, 10, v.Type().Bits()); err == nil {
+			v.SetInt(n)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var n uint64
+		if n, err = strconv.ParseUint(s, 10, v.Type().Bits()); err == nil {
+			v.SetUint(n)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(s, v.Type().Bits()); err == nil {
+			v.SetFloat(f)
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		v.SetBytes([]byte(s))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	if ne := (*strconv.NumError)(nil); errors.As(err, &ne) {
+		err = ne.Err
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %q: %w", s, err)
+	}
+	return nil
+}
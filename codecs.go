@@ -2,11 +2,15 @@ package gserv
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"sync"
 
 	"go.oneofone.dev/genh"
+	"go.oneofone.dev/gserv/internal"
 )
 
 // Common MIME types used by the codecs.
@@ -15,6 +19,7 @@ const (
 	MimeEvent      = "text/event-stream"
 	MimeMsgPack    = "application/msgpack"
 	MimeXML        = "application/xml"
+	MimeCBOR       = "application/cbor"
 	MimeJavascript = "application/javascript"
 	MimeHTML       = "text/html"
 	MimePlain      = "text/plain"
@@ -25,6 +30,8 @@ var (
 	_ Codec = (*PlainTextCodec)(nil)
 	_ Codec = (*JSONCodec)(nil)
 	_ Codec = (*MsgpCodec)(nil)
+	_ Codec = (*XMLCodec)(nil)
+	_ Codec = (*CBORCodec)(nil)
 	_ Codec = (*MixedCodec[JSONCodec, MsgpCodec])(nil)
 )
 
@@ -113,6 +120,57 @@ func (c MsgpCodec) Encode(w io.Writer, v any) error {
 	return genh.EncodeMsgpack(w, v)
 }
 
+// XMLCodec encodes and decodes data as XML using encoding/xml, GenResponse is encoded as a <response> element.
+// Unlike JSONCodec, MsgpCodec and CBORCodec it isn't registered by default, see RegisterCodec, since encoding/xml doesn't support maps.
+type XMLCodec struct{ Indent bool }
+
+func (XMLCodec) ContentType() string { return MimeXML }
+
+func (XMLCodec) Decode(r io.Reader, out any) error {
+	return xml.NewDecoder(r).Decode(out)
+}
+
+// Encode encodes data as XML to the writer, followed by a newline like JSONCodec.
+func (x XMLCodec) Encode(w io.Writer, v any) error {
+	enc := xml.NewEncoder(w)
+	if x.Indent {
+		enc.Indent("", "\t")
+	}
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// CBORCodec encodes and decodes data as CBOR (RFC 8949), struct fields use their json tags like JSONCodec,
+// []byte is encoded as a byte string and time.Time as an RFC 3339 string with tag 0.
+type CBORCodec struct{}
+
+func (CBORCodec) ContentType() string { return MimeCBOR }
+
+// Decode decodes the CBOR item read from r, it returns io.EOF if r is empty like JSONCodec.
+func (CBORCodec) Decode(r io.Reader, out any) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(b) == 0 {
+		return io.EOF
+	}
+	return internal.UnmarshalCBOR(b, out)
+}
+
+// Encode encodes data as CBOR to the writer.
+func (CBORCodec) Encode(w io.Writer, v any) error {
+	b, err := internal.MarshalCBOR(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
 // MixedCodec uses one codec for decoding and another for encoding.
 type MixedCodec[Dec, Enc Codec] struct {
 	dec Dec
@@ -130,6 +188,62 @@ func (m MixedCodec[Dec, Enc]) Encode(w io.Writer, v any) error {
 	return m.enc.Encode(w, v)
 }
 
+var codecs = struct {
+	sync.RWMutex
+	types []string
+	m     map[string]Codec
+}{
+	types: []string{MimeJSON, MimeMsgPack, MimeCBOR},
+	m:     map[string]Codec{MimeJSON: JSONCodec{}, MimeMsgPack: MsgpCodec{}, MimeCBOR: CBORCodec{}},
+}
+
+// RegisterCodec registers c for the mime type, replacing the codec registered for it if any,
+// so requests of that content type are decoded with it, see CodecFor, and responses can be negotiated to it,
+// see Context.Negotiate. JSONCodec, MsgpCodec and CBORCodec are registered by default.
+func RegisterCodec(mime string, c Codec) {
+	mime = strings.ToLower(mime)
+	codecs.Lock()
+	defer codecs.Unlock()
+	if _, ok := codecs.m[mime]; !ok {
+		codecs.types = append(codecs.types, mime)
+	}
+	codecs.m[mime] = c
+}
+
+// CodecFor returns the codec registered for the media type of contentType, ex: application/json; charset=utf-8,
+// or the one of application/<suffix> for structured syntax suffixes, ex: application/problem+json uses the codec of
+// application/json and application/vnd.x+msgpack the one of application/msgpack. Any other media type containing
+// json, ex: text/json or application/x-json, uses the codec of application/json. It returns nil if none is registered.
+func CodecFor(contentType string) Codec {
+	mt := mediaType(contentType)
+	if mt == "" {
+		return nil
+	}
+
+	codecs.RLock()
+	defer codecs.RUnlock()
+	if c := codecs.m[mt]; c != nil {
+		return c
+	}
+	if i := strings.LastIndexByte(mt, '+'); i > -1 {
+		if c := codecs.m["application/"+mt[i+1:]]; c != nil {
+			return c
+		}
+	}
+	if strings.Contains(mt, "json") {
+		return codecs.m[MimeJSON]
+	}
+	return nil
+}
+
+// requestCodec returns the codec of the request's content type, see CodecFor, or the first non-nil of defs.
+func requestCodec(ctx *Context, defs ...Codec) Codec {
+	if c := CodecFor(ctx.ContentType()); c != nil {
+		return c
+	}
+	return genh.FirstNonZero(defs...)
+}
+
 func getError(err error) HTTPError {
 	if err, ok := err.(HTTPError); ok {
 		return err
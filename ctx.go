@@ -64,6 +64,27 @@ func (ctx *Context) Route() *router.Route {
 	return router.RouteFromRequest(ctx.Req)
 }
 
+// AllowedMethods returns the methods allowed for the current path, only set in Server.MethodNotAllowedHandler.
+func (ctx *Context) AllowedMethods() []string {
+	return router.AllowedFromRequest(ctx.Req)
+}
+
+// URLFor builds the path of the route with the given name, params are key/value pairs, see Server.URL.
+// Routes of the request's host are looked up first.
+func (ctx *Context) URLFor(name string, params ...string) (string, error) {
+	return ctx.s.url(ctx.Req.Host, name, params)
+}
+
+// RedirectToRoute returns a redirect response to the route with the given name, see Redirect and URLFor.
+// If the URL can't be built, it returns a 500 error response.
+func (ctx *Context) RedirectToRoute(name string, perm bool, params ...string) Response {
+	u, err := ctx.URLFor(name, params...)
+	if err != nil {
+		return NewJSONErrorResponse(http.StatusInternalServerError, err)
+	}
+	return Redirect(u, perm)
+}
+
 // Param returns a path parameter by key name.
 func (ctx *Context) Param(key string) string {
 	return ctx.Params.Get(key)
@@ -146,44 +167,45 @@ func (ctx *Context) CloseBody() error {
 	return ctx.Req.Body.Close()
 }
 
-// BindJSON parses the request's body as JSON and closes the body.
-// Note that unlike gin.Context.Bind, this does NOT verify the fields using special tags.
+// BindJSON parses the request's body as JSON, closes the body and validates out, see Validate.
 func (ctx *Context) BindJSON(out any) error {
 	return ctx.BindCodec(JSONCodec{}, out)
 }
 
-// BindMsgpack parses the request's body as msgpack and closes the body.
-// Note that unlike gin.Context.Bind, this does NOT verify the fields using special tags.
+// BindMsgpack parses the request's body as msgpack, closes the body and validates out, see Validate.
 func (ctx *Context) BindMsgpack(out any) error {
 	return ctx.BindCodec(MsgpCodec{}, out)
 }
 
-// BindCodec parses the request's body using the given codec and closes the body.
-// Note that unlike gin.Context.BindCodec, this does NOT verify the fields using special tags.
+// BindCodec parses the request's body using the given codec, or the one of its content type if c is nil, see CodecFor,
+// closes the body and validates out, see Validate.
 func (ctx *Context) BindCodec(c Codec, out any) error {
-	c = genh.FirstNonZero(c, ctx.Codec, DefaultCodec)
+	if c == nil {
+		c = requestCodec(ctx, ctx.Codec, DefaultCodec)
+	}
 	err := c.Decode(ctx, out)
 	_ = ctx.CloseBody()
 	if errors.Is(err, io.EOF) {
 		return ErrEmptyData
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	return Validate(out)
 }
 
-// Bind parses the request's body based on its content type and closes the body.
-// Note that unlike gin.Context.Bind, this does NOT verify the fields using special tags.
+// Bind parses the request's body with the codec of its content type, see CodecFor, or ctx.Codec by default,
+// closes the body and validates out, see Validate and BindAll to bind path, query, header, cookie and form values as well.
 func (ctx *Context) Bind(out any) error {
-	var c Codec
-	ct := ctx.ContentType()
-	switch {
-	case strings.Contains(ct, "json"):
-		c = JSONCodec{}
-	case strings.Contains(ct, "msgpack"):
-		c = MsgpCodec{}
-	default:
-		c = genh.FirstNonZero(ctx.Codec, DefaultCodec)
+	if err := ctx.decodeBody(out); err != nil {
+		return err
 	}
+	return Validate(out)
+}
 
+func (ctx *Context) decodeBody(out any) error {
+	ct := ctx.ContentType()
+	c := requestCodec(ctx, ctx.Codec, DefaultCodec)
 	err := c.Decode(ctx, out)
 	_ = ctx.CloseBody()
 	if err != nil {
@@ -234,17 +256,15 @@ func (ctx *Context) EncodeCodec(c Codec, code int, v any) error {
 	return c.Encode(ctx, v)
 }
 
-// Encode encodes data using the content type of the request and writes it to the response with the given status code.
+// Encode encodes data using the codec the request accepts, see Negotiate, or the one of its content type by default, see CodecFor,
+// and writes it to the response with the given status code.
+// If none of the codecs is acceptable, it responds with a 406 error response instead, like GenResponse.WriteToCtx,
+// and returns ErrNotAcceptable.
 func (ctx *Context) Encode(code int, v any) error {
-	var c Codec
-	ct := ctx.ContentType()
-	switch {
-	case strings.Contains(ct, "json"):
-		c = JSONCodec{}
-	case strings.Contains(ct, "msgpack"):
-		c = MsgpCodec{}
-	default:
-		c = genh.FirstNonZero(ctx.Codec, DefaultCodec)
+	c, err := ctx.Negotiate(requestCodec(ctx, ctx.Codec, DefaultCodec))
+	if err != nil {
+		// the envelope is encoded with c directly, so its codec type doesn't matter
+		code, v = http.StatusNotAcceptable, NewErrorResponse[JSONCodec](http.StatusNotAcceptable, err)
 	}
 
 	ctx.done = true
@@ -253,7 +273,10 @@ func (ctx *Context) Encode(code int, v any) error {
 	if code > 0 {
 		ctx.WriteHeader(code)
 	}
-	return c.Encode(ctx, v)
+	if encErr := c.Encode(ctx, v); err == nil {
+		err = encErr
+	}
+	return err
 }
 
 // ClientIP returns the client's IP address, accounting for X-Real-Ip and X-Forwarded-For headers.
@@ -486,7 +509,9 @@ var ctxPool = sync.Pool{
 
 func getCtx(rw http.ResponseWriter, req *http.Request, p router.Params, s *Server) *Context {
 	ctx := ctxPool.Get().(*Context)
-	if !s.NoCompression && strings.Contains(req.Header.Get(acceptHeader), gzEnc) {
+
+	// a mounted server writes to the parent's Context, which already compresses
+	if _, nested := rw.(*Context); !nested && !s.NoCompression && strings.Contains(req.Header.Get(acceptHeader), gzEnc) {
 		rw = getGzipRW(rw)
 	}
 
@@ -507,6 +532,14 @@ func getCtx(rw http.ResponseWriter, req *http.Request, p router.Params, s *Serve
 		ReqQuery: q,
 	}
 
+	if s.mounted.Load() {
+		if data, ok := req.Context().Value(ctxDataKey).(M); ok {
+			for k, v := range data {
+				ctx.Set(k, v)
+			}
+		}
+	}
+
 	return ctx
 }
 
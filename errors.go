@@ -3,6 +3,7 @@ package gserv
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"go.oneofone.dev/otk"
 )
@@ -31,17 +32,20 @@ var (
 	ErrNotImpl = NewError(http.StatusNotImplemented, "not implemented")
 )
 
-// Error is a standard HTTP error with an optional caller info.
+// Error is a standard HTTP error with an optional caller info,
+// Field and In are set for errors about a single request value, ex: by ValidateRequests.
 type Error struct {
-	Caller  *callerInfo `json:"caller,omitempty"`
-	Message string      `json:"message,omitempty"`
-	Code    int         `json:"code,omitempty"`
+	Caller  *callerInfo `json:"caller,omitempty" xml:"caller,omitempty"`
+	Message string      `json:"message,omitempty" xml:"message,omitempty"`
+	Code    int         `json:"code,omitempty" xml:"code,omitempty"`
+	Field   string      `json:"field,omitempty" xml:"field,omitempty"`
+	In      string      `json:"in,omitempty" xml:"in,omitempty"`
 }
 
 type callerInfo struct {
-	Func string `json:"func,omitempty"`
-	File string `json:"file,omitempty"`
-	Line int    `json:"line,omitempty"`
+	Func string `json:"func,omitempty" xml:"func,omitempty"`
+	File string `json:"file,omitempty" xml:"file,omitempty"`
+	Line int    `json:"line,omitempty" xml:"line,omitempty"`
 }
 
 // NewError creates a new HTTPError with the given status code and message.
@@ -69,3 +73,22 @@ func NewErrorWithCaller(status int, msg string, skip int) HTTPError {
 }
 func (e Error) Status() int   { return e.Code }
 func (e Error) Error() string { return e.Message }
+
+// FieldErrors is a list of errors about request values, each with its Field and In set, ex: returned by Context.BindAll.
+// Its status is the Code of its first error, and NewErrorResponse adds each of them to the response's Errors.
+type FieldErrors []Error
+
+func (fe FieldErrors) Status() int {
+	if len(fe) > 0 && fe[0].Code != 0 {
+		return fe[0].Code
+	}
+	return http.StatusBadRequest
+}
+
+func (fe FieldErrors) Error() string {
+	msgs := make([]string, 0, len(fe))
+	for _, e := range fe {
+		msgs = append(msgs, strings.TrimSpace(e.In+" "+e.Field)+": "+e.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -4,6 +4,10 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"reflect"
+	"slices"
+
+	"go.oneofone.dev/gserv/router"
 )
 
 // GroupType is the interface that groups must satisfy for route generation functions.
@@ -27,6 +31,42 @@ func MsgpGet[Resp any, HandlerFn func(ctx *Context) (resp Resp, err error)](g Gr
 	return Get[MsgpCodec](g, path, handler, wrapResp)
 }
 
+// XMLGet creates a GET route with automatic XML request/response handling.
+func XMLGet[Resp any, HandlerFn func(ctx *Context) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return Get[XMLCodec](g, path, handler, wrapResp)
+}
+
+// CBORGet creates a GET route with automatic CBOR request/response handling.
+func CBORGet[Resp any, HandlerFn func(ctx *Context) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return Get[CBORCodec](g, path, handler, wrapResp)
+}
+
+// GetReq creates a GET route with automatic request/response handling, reqData is bound from the request's
+// path, query, header, cookie and form values, see Context.BindAll, and validated, see Validate.
+func GetReq[CodecT Codec, Req, Resp any, HandlerFn func(ctx *Context, reqData Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return handleInOut[CodecT](g, http.MethodGet, path, handler, wrapResp)
+}
+
+// JSONGetReq creates a GET route with automatic JSON request/response handling, see GetReq.
+func JSONGetReq[Req, Resp any, HandlerFn func(ctx *Context, reqData Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return GetReq[JSONCodec](g, path, handler, wrapResp)
+}
+
+// MsgpGetReq creates a GET route with automatic msgpack request/response handling, see GetReq.
+func MsgpGetReq[Req, Resp any, HandlerFn func(ctx *Context, reqData Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return GetReq[MsgpCodec](g, path, handler, wrapResp)
+}
+
+// XMLGetReq creates a GET route with automatic XML request/response handling, see GetReq.
+func XMLGetReq[Req, Resp any, HandlerFn func(ctx *Context, reqData Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return GetReq[XMLCodec](g, path, handler, wrapResp)
+}
+
+// CBORGetReq creates a GET route with automatic CBOR request/response handling, see GetReq.
+func CBORGetReq[Req, Resp any, HandlerFn func(ctx *Context, reqData Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return GetReq[CBORCodec](g, path, handler, wrapResp)
+}
+
 // Delete creates a DELETE route with automatic request/response handling.
 func Delete[CodecT Codec, Resp any, HandlerFn func(ctx *Context) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
 	return handleOutOnly[CodecT](g, http.MethodDelete, path, handler, wrapResp)
@@ -42,7 +82,46 @@ func MsgpDelete[Resp any, HandlerFn func(ctx *Context) (resp Resp, err error)](g
 	return Delete[MsgpCodec](g, path, handler, wrapResp)
 }
 
+// XMLDelete creates a DELETE route with automatic XML request/response handling.
+func XMLDelete[Resp any, HandlerFn func(ctx *Context) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return Delete[XMLCodec](g, path, handler, wrapResp)
+}
+
+// CBORDelete creates a DELETE route with automatic CBOR request/response handling.
+func CBORDelete[Resp any, HandlerFn func(ctx *Context) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return Delete[CBORCodec](g, path, handler, wrapResp)
+}
+
+// DeleteReq creates a DELETE route with automatic request/response handling, reqData is bound from the request's
+// path, query, header, cookie and form values, see Context.BindAll, and validated, see Validate.
+func DeleteReq[CodecT Codec, Req, Resp any, HandlerFn func(ctx *Context, reqData Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return handleInOut[CodecT](g, http.MethodDelete, path, handler, wrapResp)
+}
+
+// JSONDeleteReq creates a DELETE route with automatic JSON request/response handling, see DeleteReq.
+func JSONDeleteReq[Req, Resp any, HandlerFn func(ctx *Context, reqData Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return DeleteReq[JSONCodec](g, path, handler, wrapResp)
+}
+
+// MsgpDeleteReq creates a DELETE route with automatic msgpack request/response handling, see DeleteReq.
+func MsgpDeleteReq[Req, Resp any, HandlerFn func(ctx *Context, reqData Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return DeleteReq[MsgpCodec](g, path, handler, wrapResp)
+}
+
+// XMLDeleteReq creates a DELETE route with automatic XML request/response handling, see DeleteReq.
+func XMLDeleteReq[Req, Resp any, HandlerFn func(ctx *Context, reqData Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return DeleteReq[XMLCodec](g, path, handler, wrapResp)
+}
+
+// CBORDeleteReq creates a DELETE route with automatic CBOR request/response handling, see DeleteReq.
+func CBORDeleteReq[Req, Resp any, HandlerFn func(ctx *Context, reqData Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return DeleteReq[CBORCodec](g, path, handler, wrapResp)
+}
+
 // Post creates a POST route with automatic request/response handling.
+// The body is decoded with the codec of its content type, see CodecFor, or CodecT by default.
+// The fields of reqBody tagged with path, query, header, cookie or form are bound from the request as well, see Context.BindAll,
+// and it's validated before calling handler, see Validate.
 func Post[CodecT Codec, Req, Resp any, HandlerFn func(ctx *Context, reqBody Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
 	return handleInOut[CodecT](g, http.MethodPost, path, handler, wrapResp)
 }
@@ -57,6 +136,16 @@ func MsgpPost[Req, Resp any, HandlerFn func(ctx *Context, reqBody Req) (resp Res
 	return Post[MsgpCodec](g, path, handler, wrapResp)
 }
 
+// XMLPost creates a POST route with automatic XML request/response handling.
+func XMLPost[Req, Resp any, HandlerFn func(ctx *Context, reqBody Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return Post[XMLCodec](g, path, handler, wrapResp)
+}
+
+// CBORPost creates a POST route with automatic CBOR request/response handling.
+func CBORPost[Req, Resp any, HandlerFn func(ctx *Context, reqBody Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return Post[CBORCodec](g, path, handler, wrapResp)
+}
+
 // Put creates a PUT route with automatic request/response handling.
 func Put[CodecT Codec, Req, Resp any, HandlerFn func(ctx *Context, reqBody Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
 	return handleInOut[CodecT](g, http.MethodPut, path, handler, wrapResp)
@@ -72,6 +161,16 @@ func MsgpPut[Req, Resp any, HandlerFn func(ctx *Context, reqBody Req) (resp Resp
 	return Put[MsgpCodec](g, path, handler, wrapResp)
 }
 
+// XMLPut creates a PUT route with automatic XML request/response handling.
+func XMLPut[Req, Resp any, HandlerFn func(ctx *Context, reqBody Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return Put[XMLCodec](g, path, handler, wrapResp)
+}
+
+// CBORPut creates a PUT route with automatic CBOR request/response handling.
+func CBORPut[Req, Resp any, HandlerFn func(ctx *Context, reqBody Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return Put[CBORCodec](g, path, handler, wrapResp)
+}
+
 // Patch creates a PATCH route with automatic request/response handling.
 func Patch[CodecT Codec, Req, Resp any, HandlerFn func(ctx *Context, reqBody Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
 	return handleInOut[CodecT](g, http.MethodPatch, path, handler, wrapResp)
@@ -87,15 +186,29 @@ func MsgpPatch[Req, Resp any, HandlerFn func(ctx *Context, reqBody Req) (resp Re
 	return Patch[MsgpCodec](g, path, handler, wrapResp)
 }
 
+// XMLPatch creates a PATCH route with automatic XML request/response handling.
+func XMLPatch[Req, Resp any, HandlerFn func(ctx *Context, reqBody Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return Patch[XMLCodec](g, path, handler, wrapResp)
+}
+
+// CBORPatch creates a PATCH route with automatic CBOR request/response handling.
+func CBORPatch[Req, Resp any, HandlerFn func(ctx *Context, reqBody Req) (resp Resp, err error)](g GroupType, path string, handler HandlerFn, wrapResp bool) Route {
+	return Patch[CBORCodec](g, path, handler, wrapResp)
+}
+
 func handleOutOnly[CodecT Codec, Resp any, HandlerFn func(ctx *Context) (resp Resp, err error)](g GroupType, method, path string, handler HandlerFn, wrapResp bool) Route {
-	var c CodecT
 	var resp Resp
 	_, respBytes := any(resp).([]byte)
 
-	return g.AddRoute(method, path, func(ctx *Context) Response {
+	rn := g.AddRoute(method, path, func(ctx *Context) Response {
+		c, err := negotiateResp[CodecT](ctx, respBytes)
+		if err != nil {
+			return handleError[CodecT](ctx, c, err, wrapResp)
+		}
+
 		resp, err := handler(ctx)
 		if err != nil {
-			return handleError[CodecT](ctx, err, wrapResp)
+			return handleError[CodecT](ctx, c, err, wrapResp)
 		}
 		if wrapResp {
 			return NewResponse[CodecT](resp)
@@ -104,33 +217,58 @@ func handleOutOnly[CodecT Codec, Resp any, HandlerFn func(ctx *Context) (resp Re
 			_, _ = ctx.Write(any(resp).([]byte))
 			return nil
 		}
+		ctx.SetContentType(c.ContentType())
 		_ = c.Encode(ctx, resp)
 		return nil
 	})
+	return docTypes[CodecT, Resp](rn, nil, false, wrapResp)
 }
 
 func handleInOut[CodecT Codec, Req, Resp any, HandlerFn func(ctx *Context, reqBody Req) (resp Resp, err error)](g GroupType, method, path string, handler HandlerFn, wrapResp bool) Route {
-	var c CodecT
+	var def CodecT
 	var req Req
 	var resp Resp
 	_, reqBytes := any(req).([]byte)
 	_, respBytes := any(resp).([]byte)
-	return g.AddRoute(method, path, func(ctx *Context) Response {
+	reqType := reflect.TypeFor[Req]()
+	reqBody := method != http.MethodGet && method != http.MethodDelete
+	bindReq := hasBindFields(reqType)
+	rn := g.AddRoute(method, path, func(ctx *Context) Response {
+		c, err := negotiateResp[CodecT](ctx, respBytes)
+		if err != nil {
+			return handleError[CodecT](ctx, c, err, wrapResp)
+		}
+
 		var body Req
-		if reqBytes {
+		switch {
+		case reqBytes:
 			b, err := io.ReadAll(ctx.Req.Body)
 			if err != nil {
-				return handleError[CodecT](ctx, err, wrapResp)
+				return handleError[CodecT](ctx, c, err, wrapResp)
 			}
 			*(any(&body).(*[]byte)) = b
-		} else if err := c.Decode(ctx.Req.Body, &body); err != nil && !errors.Is(err, io.EOF) {
-			return handleError[CodecT](ctx, err, wrapResp)
+		case !reqBody, bindReq && isFormRequest(ctx.Req):
+		default:
+			if err := requestCodec(ctx, def).Decode(ctx.Req.Body, &body); err != nil && !errors.Is(err, io.EOF) {
+				return handleError[CodecT](ctx, c, err, wrapResp)
+			}
+		}
+
+		if bindReq {
+			if err := ctx.bindValues(reflect.ValueOf(&body).Elem()); err != nil {
+				return handleError[CodecT](ctx, c, err, wrapResp)
+			}
+		}
+		if !reqBytes {
+			if err := Validate(body); err != nil {
+				return handleError[CodecT](ctx, c, err, wrapResp)
+			}
 		}
 
 		ctx.SetContentType(c.ContentType())
 		resp, err := handler(ctx, body)
 		if err != nil {
-			return handleError[CodecT](ctx, err, wrapResp)
+			return handleError[CodecT](ctx, c, err, wrapResp)
 		}
 		if wrapResp {
 			return NewResponse[CodecT](resp)
@@ -142,15 +280,119 @@ func handleInOut[CodecT Codec, Req, Resp any, HandlerFn func(ctx *Context, reqBo
 		_ = c.Encode(ctx, resp)
 		return nil
 	})
+	return docTypes[CodecT, Resp](rn, reqType, reqBody, wrapResp)
+}
+
+// negotiateResp returns the codec the request accepts for the response, CodecT for []byte responses, see Context.Negotiate.
+func negotiateResp[CodecT Codec](ctx *Context, respBytes bool) (Codec, error) {
+	var def CodecT
+	if respBytes {
+		return def, nil
+	}
+	return ctx.Negotiate(def)
 }
 
-func handleError[C Codec](ctx *Context, e error, wrapResp bool) Response {
-	var c C
+// handleError responds with e, in a GenResponse if wrapResp is set, c is the codec of unwrapped errors.
+func handleError[CodecT Codec](ctx *Context, c Codec, e error, wrapResp bool) Response {
 	err := getError(e)
 	if wrapResp {
-		return NewErrorResponse[C](err.Status(), err)
+		return NewErrorResponse[CodecT](err.Status(), err)
 	}
+	ctx.SetContentType(c.ContentType())
 	ctx.WriteHeader(err.Status())
-	_ = c.Encode(ctx, getError(err))
+	_ = c.Encode(ctx, err)
 	return nil
 }
+
+var bytesType = reflect.TypeFor[[]byte]()
+
+// docTypes documents the params of rn bound from the fields of reqType, if it isn't nil, its request body with the schema
+// of reqType if reqBody is set, and its responses with the schemas of Resp and Error, or GenResponse if wrapResp is set,
+// under the codec's content type, see router.Router.SchemaOf.
+func docTypes[CodecT Codec, Resp any](rn Route, reqType reflect.Type, reqBody, wrapResp bool) Route {
+	if rn == nil {
+		return nil
+	}
+
+	var c CodecT
+	ct := c.ContentType()
+	if ct == "" {
+		ct = MimePlain
+	}
+	r, sr := rn.Router(), rn.Doc()
+
+	if reqType != nil {
+		docBindParams(r, sr, reqType)
+	}
+	if reqType != nil && reqBody {
+		if reqType == bytesType {
+			sr.WithBodySchema(MimeBinary, &router.SwaggerDefinition{Type: "string", Format: "binary"})
+		} else {
+			sr.WithBodySchema(ct, r.SchemaOf(reqType))
+		}
+	}
+
+	respType := reflect.TypeFor[Resp]()
+	resp, errResp := r.SchemaOf(respType), r.SchemaOf(reflect.TypeFor[Error]())
+	if wrapResp {
+		env := r.SchemaOf(reflect.TypeFor[GenResponse[CodecT]]())
+		resp = &router.SwaggerDefinition{AllOf: []*router.SwaggerDefinition{env, {
+			Type:       "object",
+			Properties: map[string]*router.SwaggerDefinition{"data": resp},
+		}}}
+		errResp = env
+	}
+
+	if respType == bytesType && !wrapResp {
+		sr.WithResponseSchema("200", http.StatusText(http.StatusOK), MimeBinary, &router.SwaggerDefinition{Type: "string", Format: "binary"})
+	} else {
+		sr.WithResponseSchema("200", http.StatusText(http.StatusOK), ct, resp)
+	}
+	sr.WithResponseSchema("default", "Error", ct, errResp)
+	return rn
+}
+
+// hasBindFields reports whether t is a struct, or a pointer to one, with fields bound by Context.BindAll.
+func hasBindFields(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && len(bindFieldsOf(t)) > 0
+}
+
+// docBindParams documents the query, header and cookie fields of t bound by Context.BindAll as params of sr,
+// with the constraints of their validate tags, and sets the schema of its path params that don't have a constraint.
+func docBindParams(r *router.Router, sr *router.SwaggerRoute, t reflect.Type) {
+	if !hasBindFields(t) {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	for _, f := range bindFieldsOf(t) {
+		if f.in == "form" {
+			continue
+		}
+
+		var schema *router.SwaggerDefinition
+		if ft := f.typ; ft == durationType || ft.Kind() == reflect.Pointer && ft.Elem() == durationType {
+			schema = &router.SwaggerDefinition{Type: "string"}
+		} else {
+			schema = r.SchemaOf(ft)
+		}
+
+		required := f.validate != "" && schema.ApplyValidateTag(f.validate)
+
+		i := slices.IndexFunc(sr.Parameters, func(p *router.SwaggerParam) bool { return p.In == f.in && p.Name == f.name })
+		if i == -1 {
+			if f.in != "path" {
+				sr = sr.WithParams([]*router.SwaggerParam{{Name: f.name, In: f.in, Schema: schema, Required: required}})
+			}
+			continue
+		}
+		if p := sr.Parameters[i]; p.In == "path" && (p.Schema == nil || p.Schema.Type == "string" && p.Schema.Format == "" && p.Schema.Pattern == "") {
+			sr = sr.WithParamSchema(p.Name, p.In, schema)
+		}
+	}
+}
@@ -2,8 +2,10 @@ package gserv
 
 import (
 	"bytes"
+	"encoding/xml"
 	"log"
 	"net/http"
+	"reflect"
 
 	"go.oneofone.dev/oerrs"
 )
@@ -24,6 +26,7 @@ func NewResponse[CodecT Codec](data any) *GenResponse[CodecT] {
 // 3. Error or *Error — appended directly.
 // 4. another Response — its Errors are appended to this response.
 // 5. MultiError — each error is recursively appended.
+// 6. FieldErrors — each error is appended directly.
 // If errs is empty, http.StatusText(code) is used as the error message.
 func NewErrorResponse[CodecT Codec](code int, errs ...any) (r *GenResponse[CodecT]) {
 	if len(errs) == 0 {
@@ -62,7 +65,9 @@ func (r GenResponse[CodecT]) Status() int {
 	return r.Code
 }
 
-// WriteToCtx writes the response's headers and body to the given Context.
+// WriteToCtx writes the response's headers and body to the given Context, encoded with the codec the request accepts,
+// CodecT by default, see Context.Negotiate. Successful responses become a 406 if none is acceptable,
+// and any response that fails to encode becomes a 500.
 func (r GenResponse[CodecT]) WriteToCtx(ctx *Context) error {
 	switch r.Code {
 	case 0:
@@ -77,13 +82,124 @@ func (r GenResponse[CodecT]) WriteToCtx(ctx *Context) error {
 		return nil
 	}
 
+	var def CodecT
+	c, err := ctx.Negotiate(def)
+	if err != nil && r.Code < http.StatusBadRequest {
+		// errors are still sent with CodecT rather than replaced with a 406
+		r = *NewErrorResponse[CodecT](http.StatusNotAcceptable, err)
+	}
+
 	r.Success = r.Code >= http.StatusOK && r.Code < http.StatusBadRequest
 
-	var c CodecT
+	// encoded before the headers are written, so a response that can't be encoded (ex: a map with XMLCodec)
+	// becomes a 500 rather than a truncated body
+	var buf bytes.Buffer
+	encErr := c.Encode(&buf, &r)
+	if encErr != nil {
+		r = *NewErrorResponse[CodecT](http.StatusInternalServerError, encErr)
+		buf.Reset()
+		if err := c.Encode(&buf, &r); err != nil {
+			buf.Reset()
+		}
+	}
+
 	ctx.SetContentType(c.ContentType())
 	ctx.WriteHeader(r.Code)
 
-	return c.Encode(ctx, &r)
+	if _, err := ctx.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return encErr
+}
+
+// MarshalXML encodes the response as a <response> element with <data>, <errors> with an <error> per error,
+// <code> and <success> elements, slices in Data are encoded as <item> elements, see XMLCodec.
+func (r GenResponse[CodecT]) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	start := xml.StartElement{Name: xml.Name{Local: "response"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	if r.Data != nil {
+		if err := marshalXMLData(e, r.Data); err != nil {
+			return err
+		}
+	}
+	if len(r.Errors) > 0 {
+		errs := struct {
+			Errors []Error `xml:"error"`
+		}{r.Errors}
+		if err := e.EncodeElement(errs, xml.StartElement{Name: xml.Name{Local: "errors"}}); err != nil {
+			return err
+		}
+	}
+	if err := e.EncodeElement(r.Code, xml.StartElement{Name: xml.Name{Local: "code"}}); err != nil {
+		return err
+	}
+	if err := e.EncodeElement(r.Success, xml.StartElement{Name: xml.Name{Local: "success"}}); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+func marshalXMLData(e *xml.Encoder, data any) error {
+	start := xml.StartElement{Name: xml.Name{Local: "data"}}
+	rv := reflect.Indirect(reflect.ValueOf(data))
+	if !rv.IsValid() {
+		return nil
+	}
+	if !isXMLItems(rv.Type()) {
+		return e.EncodeElement(data, start)
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	item := xml.StartElement{Name: xml.Name{Local: "item"}}
+	for i := range rv.Len() {
+		if err := e.EncodeElement(rv.Index(i).Interface(), item); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML decodes a response encoded by MarshalXML, the data is decoded into Data if it's a pointer, ex: &[]User{}.
+func (r *GenResponse[CodecT]) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var env struct {
+		Data *struct {
+			Inner []byte `xml:",innerxml"`
+		} `xml:"data"`
+		Errors  []Error `xml:"errors>error"`
+		Code    int     `xml:"code"`
+		Success bool    `xml:"success"`
+	}
+	if err := d.DecodeElement(&env, &start); err != nil {
+		return err
+	}
+	r.Errors, r.Code, r.Success = env.Errors, env.Code, env.Success
+
+	rv := reflect.ValueOf(r.Data)
+	if env.Data == nil || rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return nil
+	}
+
+	data := append(append([]byte("<data>"), env.Data.Inner...), "</data>"...)
+	if t := rv.Elem().Type(); isXMLItems(t) && t.Kind() == reflect.Slice {
+		items := reflect.New(reflect.StructOf([]reflect.StructField{{Name: "Items", Type: t, Tag: `xml:"item"`}}))
+		if err := xml.Unmarshal(data, items.Interface()); err != nil {
+			return err
+		}
+		rv.Elem().Set(items.Elem().Field(0))
+		return nil
+	}
+	return xml.Unmarshal(data, r.Data)
+}
+
+// isXMLItems reports whether t is a slice or array encoded as <item> elements, []byte is encoded as text.
+func isXMLItems(t reflect.Type) bool {
+	k := t.Kind()
+	return (k == reflect.Slice || k == reflect.Array) && t.Elem().Kind() != reflect.Uint8
 }
 
 // Cached returns a cached version of this response for use with the CacheableResponse interface.
@@ -119,6 +235,8 @@ func (r *GenResponse[CodecT]) appendErr(err any) {
 		r.Errors = append(r.Errors, Error{Message: string(v)})
 	case *JSONResponse:
 		r.Errors = append(r.Errors, v.Errors...)
+	case FieldErrors:
+		r.Errors = append(r.Errors, v...)
 	case MultiError:
 		for _, err := range v {
 			r.appendErr(err)
@@ -140,6 +258,12 @@ type (
 	// MsgpResponse is a GenResponse using the MsgpCodec.
 	MsgpResponse = GenResponse[MsgpCodec]
 
+	// XMLResponse is a GenResponse using the XMLCodec, see GenResponse.MarshalXML.
+	XMLResponse = GenResponse[XMLCodec]
+
+	// CBORResponse is a GenResponse using the CBORCodec.
+	CBORResponse = GenResponse[CBORCodec]
+
 	// CacheableResponse is an interface for responses that can be cached.
 	CacheableResponse interface {
 		Cached() Response
@@ -175,3 +299,23 @@ func NewMsgpResponse(data any) *MsgpResponse {
 func NewMsgpErrorResponse(code int, errs ...any) *MsgpResponse {
 	return NewErrorResponse[MsgpCodec](code, errs...)
 }
+
+// NewXMLResponse creates a new successful (code 200) XML response with the given data.
+func NewXMLResponse(data any) *XMLResponse {
+	return NewResponse[XMLCodec](data)
+}
+
+// NewXMLErrorResponse creates a new error XML response with the given status code and errors.
+func NewXMLErrorResponse(code int, errs ...any) *XMLResponse {
+	return NewErrorResponse[XMLCodec](code, errs...)
+}
+
+// NewCBORResponse creates a new successful (code 200) CBOR response with the given data.
+func NewCBORResponse(data any) *CBORResponse {
+	return NewResponse[CBORCodec](data)
+}
+
+// NewCBORErrorResponse creates a new error CBOR response with the given status code and errors.
+func NewCBORErrorResponse(code int, errs ...any) *CBORResponse {
+	return NewErrorResponse[CBORCodec](code, errs...)
+}
@@ -1,6 +1,7 @@
 package gserv
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
@@ -21,6 +22,7 @@ type Handler = func(ctx *Context) Response
 // Group is a collection of routes with shared middleware and path prefix.
 type Group struct {
 	s    *Server
+	r    *router.Router // the server's router, or the host's if the group was created by Server.Host
 	nm   string
 	path string
 	mw   []Handler
@@ -33,18 +35,32 @@ func (g *Group) Use(mw ...Handler) {
 
 // Routes returns all registered routes. Each route is returned as [group name, method, path].
 func (g *Group) Routes() [][3]string {
-	return g.s.r.GetRoutes()
+	return g.r.GetRoutes()
 }
 
 // AddRoute adds one or more handlers for the given HTTP method and path to this group.
-// It is NOT safe to call this after starting the server.
+// It is safe to call this after starting the server, see router.Router.AddRoute.
 func (g *Group) AddRoute(method, path string, handlers ...Handler) Route {
 	ghc := groupHandlerChain{
 		hc: handlers,
 		g:  g,
 	}
 	p := joinPath(g.path, path)
-	return g.s.r.AddRoute(g.nm, method, p, ghc.Serve)
+	return g.setChainInfo(g.r.AddRoute(g.nm, method, p, ghc.Serve), handlers)
+}
+
+// AddRouteE is like AddRoute, but returns an error instead of panicking if the method or path is invalid,
+// or the route conflicts with an existing one, see router.Router.AddRouteE.
+func (g *Group) AddRouteE(method, path string, handlers ...Handler) (Route, error) {
+	ghc := groupHandlerChain{
+		hc: handlers,
+		g:  g,
+	}
+	rn, err := g.r.AddRouteE(g.nm, method, joinPath(g.path, path), ghc.Serve)
+	if err != nil {
+		return nil, err
+	}
+	return g.setChainInfo(rn, handlers), nil
 }
 
 // GET registers a GET route for the given path with the specified handlers.
@@ -72,8 +88,88 @@ func (g *Group) OPTIONS(path string, handlers ...Handler) Route {
 	return g.AddRoute(http.MethodOptions, path, handlers...)
 }
 
+// DisableRoute disables or re-enables the route for the given HTTP method and pattern in this group, see router.Router.DisableRoute.
 func (g *Group) DisableRoute(method, path string, disabled bool) bool {
-	return g.s.r.DisableRoute(method, joinPath(g.path, path), disabled)
+	return g.r.DisableRoute(method, joinPath(g.path, path), disabled)
+}
+
+// RemoveRoute removes the route for the given HTTP method and path from this group and returns it, or nil if there's no such route.
+// path is the route's pattern, ex: /users/:id, it is safe to call this after starting the server.
+func (g *Group) RemoveRoute(method, path string) Route {
+	return g.r.RemoveRoute(method, joinPath(g.path, path))
+}
+
+// SetHandler replaces the handlers of the route for the given HTTP method and path, the group's middleware still applies.
+// It returns the route, or nil if there's no such route, it is safe to call this after starting the server.
+func (g *Group) SetHandler(method, path string, handlers ...Handler) Route {
+	rn := g.r.Route(method, joinPath(g.path, path))
+	if rn == nil {
+		return nil
+	}
+	ghc := groupHandlerChain{
+		hc: handlers,
+		g:  g,
+	}
+	rn.SetHandler(ghc.Serve)
+	return g.setChainInfo(rn, handlers)
+}
+
+// setChainInfo lists the group's middleware and handlers in the route's router.RouteInfo instead of the chain wrapper.
+func (g *Group) setChainInfo(rn Route, handlers []Handler) Route {
+	if rn == nil {
+		return nil
+	}
+	ci := router.ChainInfo{
+		Handlers:   make([]string, 0, len(handlers)),
+		Middleware: make([]string, 0, len(g.mw)),
+	}
+	for _, h := range handlers {
+		ci.Handlers = append(ci.Handlers, router.FuncName(h))
+	}
+	for _, h := range g.mw {
+		ci.Middleware = append(ci.Middleware, router.FuncName(h))
+	}
+	return router.SetMeta(rn, ci)
+}
+
+// RoutesInfo returns a description of every registered route of the group's router, see router.Router.Routes.
+func (g *Group) RoutesInfo() []router.RouteInfo {
+	return g.r.Routes()
+}
+
+// DebugRoutes registers a GET route that lists the routes of the group's router as a text tree,
+// or as JSON with ?format=json, see router.Router.DebugHandler. It should be behind auth middleware in production.
+func (g *Group) DebugRoutes(path string, mw ...Handler) Route {
+	h := g.r.DebugHandler()
+	return g.AddRoute(http.MethodGet, path, append(mw[:len(mw):len(mw)], func(ctx *Context) Response {
+		h(ctx, ctx.Req, nil)
+		return nil
+	})...)
+}
+
+// Mount routes every request under prefix to h, of any method that doesn't have its own route, with the prefix stripped from the path,
+// ex: mounting "/admin" serves /admin/users/1 as /users/1. The group's middleware applies, and if h is a *Server,
+// the values set on the Context by the middleware are available to its handlers.
+// The mount is listed in Routes with router.MethodAny as its method.
+func (g *Group) Mount(prefix string, h http.Handler) Route {
+	if s, ok := h.(*Server); ok {
+		s.mounted.Store(true)
+	}
+
+	return g.AddRoute(router.MethodAny, joinPath(prefix, "*mountPath"), func(ctx *Context) Response {
+		u := *ctx.Req.URL
+		u.Path, u.RawPath = "/"+ctx.Param("mountPath"), ""
+
+		rctx := ctx.Req.Context()
+		if len(ctx.data) > 0 {
+			rctx = context.WithValue(rctx, ctxDataKey, ctx.data)
+		}
+		req := ctx.Req.WithContext(rctx)
+		req.URL = &u
+
+		h.ServeHTTP(ctx, req)
+		return nil
+	})
 }
 
 // Static registers a GET route that serves static files from the given local path.
@@ -98,6 +194,7 @@ func (g *Group) SubGroup(name, path string, mw ...Handler) *Group {
 		mw:   append(g.mw[:len(g.mw):len(g.mw)], mw...),
 		path: joinPath(g.path, path),
 		s:    g.s,
+		r:    g.r,
 	}
 }
 
@@ -116,6 +213,31 @@ func joinPath(p1, p2 string) string {
 	return strings.ReplaceAll(p1+p2, "//", "/")
 }
 
+type ctxKey uint8
+
+const (
+	// routeMWKey holds the route's middleware added by RouteMW.
+	routeMWKey ctxKey = iota
+	// ctxDataKey holds the Context values of a request passed to a mounted *Server.
+	ctxDataKey
+)
+
+// RouteMW returns a middleware for router.Route.Use that runs mw after the group's middleware and before the route's handlers,
+// it's the same as adding mw to a single route group, ex:
+//
+//	srv.DELETE("/users/:id", deleteUser).Use(gserv.RouteMW(requireScopes("users:write")))
+//
+// Metadata attached to the route with router.SetMeta is available to any middleware via ctx.Route().
+func RouteMW(mw ...Handler) router.Middleware {
+	return func(next router.Handler) router.Handler {
+		return func(w http.ResponseWriter, req *http.Request, p router.Params) {
+			prev, _ := req.Context().Value(routeMWKey).([]Handler)
+			req = req.WithContext(context.WithValue(req.Context(), routeMWKey, append(prev[:len(prev):len(prev)], mw...)))
+			next(w, req, p)
+		}
+	}
+}
+
 type groupHandlerChain struct {
 	g  *Group
 	hc []Handler
@@ -124,6 +246,7 @@ type groupHandlerChain struct {
 func (ghc *groupHandlerChain) Serve(rw http.ResponseWriter, req *http.Request, p router.Params) {
 	var (
 		ctx = getCtx(rw, req, p, ghc.g.s)
+		mw  = ghc.g.mw
 
 		mwIdx, hIdx int
 
@@ -131,6 +254,10 @@ func (ghc *groupHandlerChain) Serve(rw http.ResponseWriter, req *http.Request, p
 	)
 	defer putCtx(ctx)
 
+	if rmw, _ := req.Context().Value(routeMWKey).([]Handler); len(rmw) > 0 {
+		mw = append(mw[:len(mw):len(mw)], rmw...)
+	}
+
 	if ph := ghc.g.s.PanicHandler; ph != nil {
 		catchPanic = func() {
 			if v := recover(); v != nil {
@@ -143,8 +270,8 @@ func (ghc *groupHandlerChain) Serve(rw http.ResponseWriter, req *http.Request, p
 		if catchPanic != nil {
 			defer catchPanic()
 		}
-		for mwIdx < len(ghc.g.mw) && !ctx.done {
-			h := ghc.g.mw[mwIdx]
+		for mwIdx < len(mw) && !ctx.done {
+			h := mw[mwIdx]
 			mwIdx++
 			if r := h(ctx); r != nil {
 				if r != Break {
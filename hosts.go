@@ -0,0 +1,126 @@
+package gserv
+
+import (
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Host returns a group with its own route table that only serves requests for the given host,
+// host can be a wildcard (ex: *.example.com), which like a wildcard certificate matches a single label,
+// ex: www.example.com, but not example.com or a.www.example.com, see AutoCertHosts.
+// An exact host wins over a wildcard, requests for any other host are served by the server's own routes.
+//
+// The group starts with a copy of the server's middleware and calling Host again with the same host returns the same group.
+// Registered hosts are added to the server's AutoCertHosts.
+// It's safe to call this while serving requests, like routes, hosts are replaced with an updated copy.
+func (s *Server) Host(host string) *Group {
+	host = normalizeHost(host)
+	if g := s.host(host); g != nil {
+		return g
+	}
+
+	s.hostsMux.Lock()
+	defer s.hostsMux.Unlock()
+	if g := s.host(host); g != nil {
+		return g
+	}
+
+	g := &Group{
+		s:  s,
+		r:  s.newRouter(),
+		nm: host,
+		mw: s.mw[:len(s.mw):len(s.mw)],
+	}
+
+	hosts := map[string]*Group{host: g}
+	if old := s.hosts.Load(); old != nil {
+		maps.Copy(hosts, *old)
+	}
+	s.hosts.Store(&hosts)
+
+	if s.ach != nil {
+		s.ach.Set(host)
+	}
+	return g
+}
+
+// host returns the group registered for the normalized host, or nil.
+func (s *Server) host(host string) *Group {
+	if hosts := s.hosts.Load(); hosts != nil {
+		return (*hosts)[host]
+	}
+	return nil
+}
+
+// Hosts returns all the hosts registered with Host.
+func (s *Server) Hosts() []string {
+	hosts := s.hosts.Load()
+	if hosts == nil {
+		return []string{}
+	}
+	return slices.Collect(maps.Keys(*hosts))
+}
+
+// AutoCertHosts returns a whitelist of all the hosts registered with Host, it's kept in sync with any hosts added later,
+// ex: s.RunAutoCertDyn(ctx, &AutoCertOpts{Hosts: s.AutoCertHosts().IsAllowed}).
+func (s *Server) AutoCertHosts() *AutoCertHosts {
+	s.hostsMux.Lock()
+	defer s.hostsMux.Unlock()
+	if s.ach == nil {
+		s.ach = NewAutoCertHosts(s.Hosts()...)
+	}
+	return s.ach
+}
+
+// URL builds the path of the route with the given name, params are key/value pairs, see router.Router.URL.
+// Names are looked up in the server's own routes, then in the routes of each Host.
+func (s *Server) URL(name string, params ...string) (string, error) {
+	return s.url("", name, params)
+}
+
+// url is URL, looking up the routes of host first.
+func (s *Server) url(host, name string, params []string) (string, error) {
+	if g := s.hostGroup(host); g != nil && g.r.Named(name) != nil {
+		return g.r.URL(name, params...)
+	}
+
+	if hosts := s.hosts.Load(); hosts != nil && s.r.Named(name) == nil {
+		for _, g := range *hosts {
+			if g.r.Named(name) != nil {
+				return g.r.URL(name, params...)
+			}
+		}
+	}
+	return s.r.URL(name, params...)
+}
+
+// hostGroup returns the group registered for host, host can include a port, or nil.
+func (s *Server) hostGroup(host string) *Group {
+	host = normalizeHost(host)
+	if g := s.host(host); g != nil {
+		return g
+	}
+
+	if i := strings.IndexByte(host, '.'); i != -1 {
+		return s.host("*." + host[i+1:])
+	}
+	return nil
+}
+
+func (s *Server) hostRouter(host string) http.Handler {
+	if g := s.hostGroup(host); g != nil {
+		return g.r
+	}
+	return s.r
+}
+
+// normalizeHost lowercases host and strips the port and trailing dot if any.
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
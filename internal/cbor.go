@@ -0,0 +1,992 @@
+package internal
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CBOR major types, see RFC 8949 section 3.1.
+const (
+	cborUint byte = iota
+	cborNegInt
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+const (
+	cborFalse     = 0xf4
+	cborTrue      = 0xf5
+	cborNull      = 0xf6
+	cborUndefined = 0xf7
+	cborBreak     = 0xff
+
+	cborIndefinite = 31
+	cborMaxDepth   = 1000
+)
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// MarshalCBOR encodes v as CBOR (RFC 8949) with the preferred serialization: the shortest lengths and floats
+// that keep their values, and map keys in the bytewise order of their encoding.
+//
+// Values are encoded like encoding/json would: struct fields follow the json tags, including omitempty, omitzero
+// and embedded structs, encoding.TextMarshaler values are text, and nil pointers, slices and maps are null.
+// []byte is a byte string and time.Time an RFC 3339 string with tag 0.
+func MarshalCBOR(v any) ([]byte, error) {
+	var e cborEncoder
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// UnmarshalCBOR decodes the CBOR item in data into v, a non-nil pointer, following the same rules as MarshalCBOR.
+// Struct fields are matched by name like encoding/json, exactly then case-insensitively, and unknown keys are skipped.
+// Items decoded into an any are bool, int64, uint64 (if it doesn't fit an int64), float64, string, []byte, time.Time,
+// []any, map[string]any (or map[any]any if it has keys that aren't text) or nil, indefinite lengths are supported.
+func UnmarshalCBOR(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("cbor: UnmarshalCBOR needs a non-nil pointer, got %T", v)
+	}
+
+	d := cborDecoder{data: data}
+	if err := d.decode(rv.Elem()); err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return fmt.Errorf("cbor: %d bytes of extra data after the item", len(d.data)-d.pos)
+	}
+	return nil
+}
+
+type cborEncoder struct {
+	buf   []byte
+	depth int
+}
+
+func (e *cborEncoder) head(major byte, n uint64) {
+	m := major << 5
+	switch {
+	case n < 24:
+		e.buf = append(e.buf, m|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, m|24, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, m|25), uint16(n))
+	case n <= math.MaxUint32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, m|26), uint32(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, m|27), n)
+	}
+}
+
+func (e *cborEncoder) text(s string) {
+	e.head(cborText, uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *cborEncoder) int(n int64) {
+	if n < 0 {
+		e.head(cborNegInt, uint64(-1-n))
+		return
+	}
+	e.head(cborUint, uint64(n))
+}
+
+// float writes f as the shortest of half, single and double precision floats that keeps its value.
+func (e *cborEncoder) float(f float64) {
+	if f32 := float32(f); float64(f32) == f || math.IsNaN(f) {
+		if h, ok := float16Bits(f32); ok {
+			e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xf9), h)
+			return
+		}
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xfa), math.Float32bits(f32))
+		return
+	}
+	e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xfb), math.Float64bits(f))
+}
+
+func (e *cborEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf = append(e.buf, cborNull)
+		return nil
+	}
+
+	if e.depth++; e.depth > cborMaxDepth {
+		return errors.New("cbor: exceeded max depth, the value might be cyclic")
+	}
+	defer func() { e.depth-- }()
+
+	t := v.Type()
+	switch {
+	case t == timeType:
+		// like encoding/json, years outside of [0,9999] are an error
+		b, err := v.Interface().(time.Time).MarshalText()
+		if err != nil {
+			return fmt.Errorf("cbor: %w", err)
+		}
+		e.head(cborTag, 0)
+		e.text(string(b))
+		return nil
+	case t.Implements(textMarshalerType) && t.Kind() != reflect.Interface:
+		if t.Kind() == reflect.Pointer && v.IsNil() {
+			e.buf = append(e.buf, cborNull)
+			return nil
+		}
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		e.text(string(b))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, cborTrue)
+		} else {
+			e.buf = append(e.buf, cborFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.int(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.head(cborUint, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		e.float(v.Float())
+	case reflect.String:
+		e.text(v.String())
+
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			e.buf = append(e.buf, cborNull)
+			return nil
+		}
+		return e.encode(v.Elem())
+
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf = append(e.buf, cborNull)
+			return nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 && !t.Elem().Implements(textMarshalerType) {
+			e.head(cborBytes, uint64(v.Len()))
+			e.buf = append(e.buf, v.Bytes()...)
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		e.head(cborArray, uint64(v.Len()))
+		for i := range v.Len() {
+			if err := e.encode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf = append(e.buf, cborNull)
+			return nil
+		}
+		return e.encodeMap(v)
+
+	case reflect.Struct:
+		return e.encodeStruct(v)
+
+	default:
+		return fmt.Errorf("cbor: unsupported type %s", t)
+	}
+	return nil
+}
+
+func (e *cborEncoder) encodeMap(v reflect.Value) error {
+	type entry struct {
+		key []byte
+		val reflect.Value
+	}
+
+	entries := make([]entry, 0, v.Len())
+	for it := v.MapRange(); it.Next(); {
+		ke := cborEncoder{depth: e.depth}
+		if err := ke.encode(it.Key()); err != nil {
+			return err
+		}
+		entries = append(entries, entry{ke.buf, it.Value()})
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return bytes.Compare(a.key, b.key) })
+
+	e.head(cborMap, uint64(len(entries)))
+	for _, ent := range entries {
+		e.buf = append(e.buf, ent.key...)
+		if err := e.encode(ent.val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *cborEncoder) encodeStruct(v reflect.Value) error {
+	fields := cborFieldsOf(v.Type())
+	vals := make([]reflect.Value, len(fields))
+	n := 0
+	for i, f := range fields {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || f.omitEmpty && isEmptyValue(fv) || f.omitZero && isZeroValue(fv) {
+			continue
+		}
+		vals[i] = fv
+		n++
+	}
+
+	e.head(cborMap, uint64(n))
+	for i, f := range fields {
+		if !vals[i].IsValid() {
+			continue
+		}
+		e.text(f.name)
+		if err := e.encode(vals[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldByIndex returns the field of v at index, ok is false if it's in an embedded struct pointer that is nil.
+func fieldByIndex(v reflect.Value, index []int) (_ reflect.Value, ok bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmptyValue reports whether v is empty for omitempty, like encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// isZeroValue reports whether v is zero for omitzero, using its IsZero method if it has one, like encoding/json.
+func isZeroValue(v reflect.Value) bool {
+	if z, ok := v.Interface().(interface{ IsZero() bool }); ok {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return true
+		}
+		return z.IsZero()
+	}
+	return v.IsZero()
+}
+
+type cborField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	omitZero  bool
+	tagged    bool
+	depth     int
+}
+
+var cborFieldsCache sync.Map // reflect.Type -> []cborField
+
+// cborFieldsOf returns the fields of the struct type t as encoding/json would encode them, in order.
+func cborFieldsOf(t reflect.Type) []cborField {
+	if fs, ok := cborFieldsCache.Load(t); ok {
+		return fs.([]cborField)
+	}
+
+	var all []cborField
+	collectCBORFields(t, nil, 0, map[reflect.Type]bool{}, &all)
+
+	// the least nested field wins, then the tagged one, fields that are still ambiguous are dropped like encoding/json
+	byName := map[string][]cborField{}
+	for _, f := range all {
+		byName[f.name] = append(byName[f.name], f)
+	}
+	fs := make([]cborField, 0, len(byName))
+	for _, f := range all {
+		cands := byName[f.name]
+		if cands == nil {
+			continue
+		}
+		delete(byName, f.name)
+		if best, ok := dominantField(cands); ok {
+			fs = append(fs, best)
+		}
+	}
+	slices.SortStableFunc(fs, func(a, b cborField) int { return slices.Compare(a.index, b.index) })
+
+	cborFieldsCache.Store(t, fs)
+	return fs
+}
+
+func dominantField(cands []cborField) (cborField, bool) {
+	minDepth := slices.MinFunc(cands, func(a, b cborField) int { return a.depth - b.depth }).depth
+	var top []cborField
+	for _, f := range cands {
+		if f.depth == minDepth {
+			top = append(top, f)
+		}
+	}
+	if len(top) == 1 {
+		return top[0], true
+	}
+	var tagged []cborField
+	for _, f := range top {
+		if f.tagged {
+			tagged = append(tagged, f)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return cborField{}, false
+}
+
+func collectCBORFields(t reflect.Type, index []int, depth int, seen map[reflect.Type]bool, fs *[]cborField) {
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		idx := append(slices.Clip(index), i)
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			isPtr := ft.Kind() == reflect.Pointer
+			if isPtr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				// fields of unexported embedded struct pointers can't be allocated when decoding
+				if !isPtr || f.IsExported() {
+					collectCBORFields(ft, idx, depth+1, seen, fs)
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		cf := cborField{name: name, index: idx, depth: depth, tagged: name != ""}
+		if name == "" {
+			cf.name = f.Name
+		}
+		for opt := range strings.SplitSeq(opts, ",") {
+			switch opt {
+			case "omitempty":
+				cf.omitEmpty = true
+			case "omitzero":
+				cf.omitZero = true
+			}
+		}
+		*fs = append(*fs, cf)
+	}
+}
+
+// float16Bits returns the half precision bits of f if it can be represented exactly, NaNs are canonical.
+func float16Bits(f float32) (uint16, bool) {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23&0xff) - 127
+	mant := b & 0x7fffff
+
+	switch {
+	case b&0x7fffffff == 0:
+		return sign, true
+	case exp == 128:
+		if mant == 0 {
+			return sign | 0x7c00, true
+		}
+		return 0x7e00, true
+	case exp >= -14 && exp <= 15:
+		if mant&0x1fff != 0 {
+			return 0, false
+		}
+		return sign | uint16(exp+15)<<10 | uint16(mant>>13), true
+	case exp >= -24 && exp < -14:
+		full, shift := mant|0x800000, uint(-exp-1)
+		if full&(1<<shift-1) != 0 {
+			return 0, false
+		}
+		return sign | uint16(full>>shift), true
+	}
+	return 0, false
+}
+
+func float16ToFloat64(h uint16) float64 {
+	exp, mant := int(h>>10&0x1f), float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+type cborDecoder struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+var errCBORTruncated = errors.New("cbor: unexpected end of data")
+
+// head reads the head of the next item, arg is its argument, ex: the length of strings, ai is 31 for indefinite lengths.
+func (d *cborDecoder) head() (major, ai byte, arg uint64, err error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, 0, errCBORTruncated
+	}
+	b := d.data[d.pos]
+	d.pos++
+	major, ai = b>>5, b&0x1f
+
+	n := 0
+	switch {
+	case ai < 24:
+		return major, ai, uint64(ai), nil
+	case ai <= 27:
+		n = 1 << (ai - 24)
+	case ai == cborIndefinite && (major >= cborBytes && major <= cborMap || major == cborSimple):
+		return major, ai, 0, nil
+	default:
+		return 0, 0, 0, fmt.Errorf("cbor: invalid additional info %d for major type %d", ai, major)
+	}
+
+	if len(d.data)-d.pos < n {
+		return 0, 0, 0, errCBORTruncated
+	}
+	for _, c := range d.data[d.pos : d.pos+n] {
+		arg = arg<<8 | uint64(c)
+	}
+	d.pos += n
+	return major, ai, arg, nil
+}
+
+// peek returns the next byte without consuming it.
+func (d *cborDecoder) peek() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, errCBORTruncated
+	}
+	return d.data[d.pos], nil
+}
+
+// isBreak consumes the break of an indefinite length item if it's next.
+func (d *cborDecoder) isBreak() (bool, error) {
+	b, err := d.peek()
+	if err == nil && b == cborBreak {
+		d.pos++
+		return true, nil
+	}
+	return false, err
+}
+
+// length checks the length of an item with n entries of at least size bytes each against the remaining data.
+func (d *cborDecoder) length(n uint64, size int) (int, error) {
+	if n > uint64(len(d.data)-d.pos)/uint64(size) {
+		return 0, errCBORTruncated
+	}
+	return int(n), nil
+}
+
+// str reads the content of a byte or text string, after its head, joining the chunks of indefinite length ones.
+func (d *cborDecoder) str(major, ai byte, arg uint64) ([]byte, error) {
+	if ai != cborIndefinite {
+		n, err := d.length(arg, 1)
+		if err != nil {
+			return nil, err
+		}
+		b := d.data[d.pos : d.pos+n]
+		d.pos += n
+		return b, nil
+	}
+
+	var b []byte
+	for {
+		if brk, err := d.isBreak(); err != nil || brk {
+			return b, err
+		}
+		cm, cai, carg, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if cm != major || cai == cborIndefinite {
+			return nil, errors.New("cbor: invalid chunk in an indefinite length string")
+		}
+		chunk, err := d.str(cm, cai, carg)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, chunk...)
+	}
+}
+
+// items calls fn for each entry of an array or map, after its head.
+func (d *cborDecoder) items(ai byte, arg uint64, size int, fn func(i int) error) error {
+	if ai == cborIndefinite {
+		for i := 0; ; i++ {
+			if brk, err := d.isBreak(); err != nil || brk {
+				return err
+			}
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+	}
+
+	n, err := d.length(arg, size)
+	if err != nil {
+		return err
+	}
+	for i := range n {
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *cborDecoder) decode(v reflect.Value) error {
+	if d.depth++; d.depth > cborMaxDepth {
+		return errors.New("cbor: exceeded max depth")
+	}
+	defer func() { d.depth-- }()
+
+	b, err := d.peek()
+	if err != nil {
+		return err
+	}
+	if b == cborNull || b == cborUndefined {
+		d.pos++
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+			v.SetZero()
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(v.Elem())
+
+	case reflect.Interface:
+		// like encoding/json, decode into the non-nil pointer an interface holds
+		if e := v.Elem(); !v.IsNil() && e.Kind() == reflect.Pointer && !e.IsNil() {
+			return d.decode(e)
+		}
+		if v.NumMethod() > 0 {
+			return fmt.Errorf("cbor: cannot decode into %s", v.Type())
+		}
+		val, err := d.decodeAny()
+		if err != nil {
+			return err
+		}
+		if val == nil {
+			v.SetZero()
+		} else {
+			v.Set(reflect.ValueOf(val))
+		}
+		return nil
+	}
+
+	if v.Type() == timeType {
+		val, err := d.decodeAny()
+		if err != nil {
+			return err
+		}
+		switch val := val.(type) {
+		case time.Time:
+			v.Set(reflect.ValueOf(val))
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, val)
+			if err != nil {
+				return fmt.Errorf("cbor: %w", err)
+			}
+			v.Set(reflect.ValueOf(t))
+		default:
+			return fmt.Errorf("cbor: cannot decode %T into time.Time", val)
+		}
+		return nil
+	}
+
+	major, ai, arg, err := d.head()
+	if err != nil {
+		return err
+	}
+	for major == cborTag {
+		// tags other than the time ones are skipped
+		if major, ai, arg, err = d.head(); err != nil {
+			return err
+		}
+	}
+
+	if major == cborText && reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		s, err := d.str(major, ai, arg)
+		if err != nil {
+			return err
+		}
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText(s)
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("cbor: cannot decode major type %d into %s", major, v.Type())
+	}
+
+	switch major {
+	case cborUint, cborNegInt:
+		return d.setInt(v, major, arg)
+
+	case cborBytes, cborText:
+		s, err := d.str(major, ai, arg)
+		if err != nil {
+			return err
+		}
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(string(s))
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(bytes.Clone(s))
+		case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+			reflect.Copy(v, reflect.ValueOf(s))
+		default:
+			return mismatch()
+		}
+
+	case cborArray:
+		switch v.Kind() {
+		case reflect.Slice:
+			if ai != cborIndefinite {
+				n, err := d.length(arg, 1)
+				if err != nil {
+					return err
+				}
+				v.Set(reflect.MakeSlice(v.Type(), n, n))
+			} else {
+				v.Set(v.Slice(0, 0))
+			}
+			return d.items(ai, arg, 1, func(i int) error {
+				if i >= v.Len() {
+					v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+				}
+				return d.decode(v.Index(i))
+			})
+		case reflect.Array:
+			v.SetZero()
+			return d.items(ai, arg, 1, func(i int) error {
+				if i >= v.Len() {
+					_, err := d.decodeAny()
+					return err
+				}
+				return d.decode(v.Index(i))
+			})
+		default:
+			return mismatch()
+		}
+
+	case cborMap:
+		switch v.Kind() {
+		case reflect.Map:
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			kt, vt := v.Type().Key(), v.Type().Elem()
+			return d.items(ai, arg, 2, func(int) error {
+				k, val := reflect.New(kt).Elem(), reflect.New(vt).Elem()
+				if err := d.decode(k); err != nil {
+					return err
+				}
+				if kt.Kind() == reflect.Interface && !k.IsNil() && !k.Elem().Type().Comparable() {
+					return fmt.Errorf("cbor: unsupported map key %s", k.Elem().Type())
+				}
+				if err := d.decode(val); err != nil {
+					return err
+				}
+				v.SetMapIndex(k, val)
+				return nil
+			})
+		case reflect.Struct:
+			return d.decodeStruct(v, ai, arg)
+		default:
+			return mismatch()
+		}
+
+	case cborSimple:
+		switch {
+		case ai < 24 && (arg == cborFalse&0x1f || arg == cborTrue&0x1f):
+			if v.Kind() != reflect.Bool {
+				return mismatch()
+			}
+			v.SetBool(arg == cborTrue&0x1f)
+		case ai >= 25 && ai <= 27:
+			if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+				return mismatch()
+			}
+			v.SetFloat(cborFloat(ai, arg))
+		default:
+			return fmt.Errorf("cbor: unsupported simple value %d", arg)
+		}
+	}
+	return nil
+}
+
+func (d *cborDecoder) setInt(v reflect.Value, major byte, arg uint64) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if arg > math.MaxInt64 {
+			return fmt.Errorf("cbor: integer overflows %s", v.Type())
+		}
+		n := int64(arg)
+		if major == cborNegInt {
+			n = -1 - n
+		}
+		if v.OverflowInt(n) {
+			return fmt.Errorf("cbor: %d overflows %s", n, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if major == cborNegInt || v.OverflowUint(arg) {
+			return fmt.Errorf("cbor: integer overflows %s", v.Type())
+		}
+		v.SetUint(arg)
+	case reflect.Float32, reflect.Float64:
+		f := float64(arg)
+		if major == cborNegInt {
+			f = -1 - f
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("cbor: cannot decode an integer into %s", v.Type())
+	}
+	return nil
+}
+
+func (d *cborDecoder) decodeStruct(v reflect.Value, ai byte, arg uint64) error {
+	fields := cborFieldsOf(v.Type())
+	return d.items(ai, arg, 2, func(int) error {
+		var key string
+		if err := d.decode(reflect.ValueOf(&key).Elem()); err != nil {
+			return err
+		}
+
+		i := slices.IndexFunc(fields, func(f cborField) bool { return f.name == key })
+		if i == -1 {
+			i = slices.IndexFunc(fields, func(f cborField) bool { return strings.EqualFold(f.name, key) })
+		}
+		if i == -1 {
+			_, err := d.decodeAny()
+			return err
+		}
+
+		fv := v
+		for j, x := range fields[i].index {
+			if j > 0 && fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					fv.Set(reflect.New(fv.Type().Elem()))
+				}
+				fv = fv.Elem()
+			}
+			fv = fv.Field(x)
+		}
+		return d.decode(fv)
+	})
+}
+
+func cborFloat(ai byte, arg uint64) float64 {
+	switch ai {
+	case 25:
+		return float16ToFloat64(uint16(arg))
+	case 26:
+		return float64(math.Float32frombits(uint32(arg)))
+	default:
+		return math.Float64frombits(arg)
+	}
+}
+
+// decodeAny decodes the next item as a generic value, see UnmarshalCBOR.
+func (d *cborDecoder) decodeAny() (any, error) {
+	if d.depth++; d.depth > cborMaxDepth {
+		return nil, errors.New("cbor: exceeded max depth")
+	}
+	defer func() { d.depth-- }()
+
+	major, ai, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUint:
+		if arg > math.MaxInt64 {
+			return arg, nil
+		}
+		return int64(arg), nil
+	case cborNegInt:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor: negative integer overflows int64")
+		}
+		return -1 - int64(arg), nil
+	case cborBytes:
+		b, err := d.str(major, ai, arg)
+		return bytes.Clone(b), err
+	case cborText:
+		b, err := d.str(major, ai, arg)
+		return string(b), err
+
+	case cborArray:
+		var arr []any
+		if ai != cborIndefinite {
+			n, err := d.length(arg, 1)
+			if err != nil {
+				return nil, err
+			}
+			arr = make([]any, 0, n)
+		}
+		err := d.items(ai, arg, 1, func(int) error {
+			val, err := d.decodeAny()
+			arr = append(arr, val)
+			return err
+		})
+		if arr == nil && err == nil {
+			arr = []any{}
+		}
+		return arr, err
+
+	case cborMap:
+		m := map[string]any{}
+		var mm map[any]any // used once a key isn't text
+		err := d.items(ai, arg, 2, func(int) error {
+			k, err := d.decodeAny()
+			if err != nil {
+				return err
+			}
+			val, err := d.decodeAny()
+			if err != nil {
+				return err
+			}
+			if ks, ok := k.(string); ok && mm == nil {
+				m[ks] = val
+				return nil
+			}
+			if k == nil || !reflect.TypeOf(k).Comparable() {
+				return fmt.Errorf("cbor: unsupported map key %T", k)
+			}
+			if mm == nil {
+				mm = make(map[any]any, len(m)+1)
+				for ks, v := range m {
+					mm[ks] = v
+				}
+			}
+			mm[k] = val
+			return nil
+		})
+		if mm != nil {
+			return mm, err
+		}
+		return m, err
+
+	case cborTag:
+		val, err := d.decodeAny()
+		if err != nil {
+			return nil, err
+		}
+		switch arg {
+		case 0:
+			if s, ok := val.(string); ok {
+				t, err := time.Parse(time.RFC3339Nano, s)
+				if err != nil {
+					return nil, fmt.Errorf("cbor: %w", err)
+				}
+				return t, nil
+			}
+		case 1:
+			var t time.Time
+			switch n := val.(type) {
+			case int64:
+				t = time.Unix(n, 0).UTC()
+			case uint64:
+				return nil, errors.New("cbor: epoch time out of range")
+			case float64:
+				if math.IsNaN(n) || math.Abs(n) > 1<<62 {
+					return nil, errors.New("cbor: epoch time out of range")
+				}
+				sec, frac := math.Modf(n)
+				t = time.Unix(int64(sec), int64(frac*1e9)).UTC()
+			default:
+				return val, nil
+			}
+			// the years MarshalCBOR can encode
+			if y := t.Year(); y < 0 || y > 9999 {
+				return nil, errors.New("cbor: epoch time out of range")
+			}
+			return t, nil
+		}
+		return val, nil
+
+	default: // cborSimple
+		switch {
+		case ai == cborFalse&0x1f:
+			return false, nil
+		case ai == cborTrue&0x1f:
+			return true, nil
+		case ai == cborNull&0x1f, ai == cborUndefined&0x1f:
+			return nil, nil
+		case ai >= 25 && ai <= 27:
+			return cborFloat(ai, arg), nil
+		case ai == cborIndefinite:
+			return nil, errors.New("cbor: unexpected break")
+		}
+		return nil, fmt.Errorf("cbor: unsupported simple value %d", arg)
+	}
+}
@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// RFC 8949 appendix A, decoded into an any
+var cborVectors = []struct {
+	hex string
+	v   any
+}{
+	{"00", int64(0)}, {"01", int64(1)}, {"0a", int64(10)}, {"17", int64(23)}, {"1818", int64(24)}, {"1819", int64(25)},
+	{"1864", int64(100)}, {"1903e8", int64(1000)}, {"1a000f4240", int64(1000000)}, {"1b000000e8d4a51000", int64(1000000000000)},
+	{"1bffffffffffffffff", uint64(18446744073709551615)},
+	{"20", int64(-1)}, {"29", int64(-10)}, {"3863", int64(-100)}, {"3903e7", int64(-1000)},
+	{"f90000", 0.0}, {"f93c00", 1.0}, {"fb3ff199999999999a", 1.1}, {"f93e00", 1.5}, {"f97bff", 65504.0},
+	{"fa47c35000", 100000.0}, {"fa7f7fffff", 3.4028234663852886e+38}, {"fb7e37e43c8800759c", 1.0e+300},
+	{"f90001", 5.960464477539063e-8}, {"f90400", 0.00006103515625}, {"f9c400", -4.0}, {"fbc010666666666666", -4.1},
+	{"f97c00", math.Inf(1)}, {"f9fc00", math.Inf(-1)}, {"fa7f800000", math.Inf(1)}, {"fb7ff0000000000000", math.Inf(1)},
+	{"f4", false}, {"f5", true}, {"f6", nil}, {"f7", nil},
+	{"c074323031332d30332d32315432303a30343a30305a", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+	{"c11a514b67b0", time.Unix(1363896240, 0).UTC()},
+	{"c1fb41d452d9ec200000", time.Unix(1363896240, 5e8).UTC()},
+	{"d74401020304", []byte{1, 2, 3, 4}},
+	{"d818456449455446", []byte("dIETF")},
+	{"d82076687474703a2f2f7777772e6578616d706c652e636f6d", "http://www.example.com"},
+	{"40", []byte{}}, {"4401020304", []byte{1, 2, 3, 4}},
+	{"60", ""}, {"6161", "a"}, {"6449455446", "IETF"}, {"62225c", "\"\\"}, {"62c3bc", "ü"}, {"63e6b0b4", "水"}, {"64f0908591", "𐅑"},
+	{"80", []any{}}, {"83010203", []any{int64(1), int64(2), int64(3)}},
+	{"8301820203820405", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+	{"a0", map[string]any{}},
+	{"a201020304", map[any]any{int64(1): int64(2), int64(3): int64(4)}},
+	{"a26161016162820203", map[string]any{"a": int64(1), "b": []any{int64(2), int64(3)}}},
+	{"826161a161626163", []any{"a", map[string]any{"b": "c"}}},
+	{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+	{"7f657374726561646d696e67ff", "streaming"},
+	{"9fff", []any{}},
+	{"9f018202039f0405ffff", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+	{"9f01820203820405ff", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+	{"bf61610161629f0203ffff", map[string]any{"a": int64(1), "b": []any{int64(2), int64(3)}}},
+	{"bf6346756ef563416d7421ff", map[string]any{"Fun": true, "Amt": int64(-2)}},
+}
+
+func TestCBORDecodeVectors(t *testing.T) {
+	for _, tc := range cborVectors {
+		var v any
+		if err := UnmarshalCBOR(mustHex(t, tc.hex), &v); err != nil {
+			t.Errorf("%s: %v", tc.hex, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, tc.v) {
+			t.Errorf("%s: expected %#v, got %#v", tc.hex, tc.v, v)
+		}
+	}
+
+	var v any
+	if err := UnmarshalCBOR(mustHex(t, "f97e00"), &v); err != nil || !math.IsNaN(v.(float64)) {
+		t.Fatalf("f97e00: expected NaN, got %v (%v)", v, err)
+	}
+	if err := UnmarshalCBOR(mustHex(t, "f98000"), &v); err != nil || !math.Signbit(v.(float64)) {
+		t.Fatalf("f98000: expected -0, got %v (%v)", v, err)
+	}
+}
+
+func TestCBORDecodeTyped(t *testing.T) {
+	// floats whose bits are the simple values of false and true
+	for hex, exp := range map[string]float64{
+		"f90014":     float16ToFloat64(0x14),
+		"f90015":     float16ToFloat64(0x15),
+		"fa00000014": float64(math.Float32frombits(0x14)),
+		"f5":         math.NaN(), // true isn't a float
+	} {
+		var f float64
+		err := UnmarshalCBOR(mustHex(t, hex), &f)
+		if math.IsNaN(exp) {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %v", hex, f)
+			}
+			continue
+		}
+		if err != nil || f != exp {
+			t.Errorf("%s: expected %v, got %v (%v)", hex, exp, f, err)
+		}
+
+		var b bool
+		if err := UnmarshalCBOR(mustHex(t, hex), &b); err == nil {
+			t.Errorf("%s: expected an error decoding a float into a bool, got %v", hex, b)
+		}
+	}
+
+	var b bool
+	if err := UnmarshalCBOR(mustHex(t, "f5"), &b); err != nil || !b {
+		t.Fatalf("f5: expected true, got %v (%v)", b, err)
+	}
+
+	type user struct {
+		ID   int64    `json:"id"`
+		Name string   `json:"name"`
+		Tags []string `json:"tags,omitempty"`
+	}
+	var u user
+	if err := UnmarshalCBOR(mustHex(t, "a362696401646e616d6563626f6263657874f5"), &u); err != nil || u.ID != 1 || u.Name != "bob" {
+		t.Fatalf("unexpected user: %+v (%v)", u, err)
+	}
+
+	for _, hex := range []string{
+		"",                     // no item
+		"19ff",                 // truncated argument
+		"62c3",                 // truncated string
+		"83010203ff",           // extra data
+		"1c",                   // reserved additional info
+		"ff",                   // unexpected break
+		"5f6161ff",             // text chunk in an indefinite byte string
+		"9b7fffffffffffffff",   // array length larger than the data
+		"c1fa58303030",         // epoch time after year 9999
+		"c11bffffffffffffffff", // epoch time overflowing an int64
+	} {
+		var v any
+		if err := UnmarshalCBOR(mustHex(t, hex), &v); err == nil {
+			t.Errorf("%q: expected an error, got %#v", hex, v)
+		}
+	}
+}
+
+func FuzzCBORDecode(f *testing.F) {
+	for _, tc := range cborVectors {
+		f.Add(mustHex(f, tc.hex))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var v any
+		if err := UnmarshalCBOR(data, &v); err != nil {
+			return
+		}
+		b, err := MarshalCBOR(v)
+		if err != nil {
+			t.Fatalf("%x: decoded %#v but couldn't encode it: %v", data, v, err)
+		}
+		var v2 any
+		if err := UnmarshalCBOR(b, &v2); err != nil {
+			t.Fatalf("%x: couldn't decode the re-encoded %x: %v", data, b, err)
+		}
+
+		var s struct {
+			A int64             `json:"a"`
+			B []string          `json:"b"`
+			C map[string]uint16 `json:"c"`
+			D *float32          `json:"d"`
+		}
+		_ = UnmarshalCBOR(data, &s)
+	})
+}
+
+func mustHex(tb testing.TB, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return b
+}
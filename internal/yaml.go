@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// JSONToYAML writes the JSON document data as block style YAML, keeping the order of object keys.
+func JSONToYAML(w io.Writer, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	y := yamlWriter{dec: dec, w: bufio.NewWriter(w)}
+	switch tok {
+	case json.Delim('{'):
+		err = y.mapping(0, false)
+	case json.Delim('['):
+		err = y.sequence(0)
+	default:
+		y.w.WriteString(yamlScalar(tok) + "\n")
+	}
+	if err != nil {
+		return err
+	}
+	return y.w.Flush()
+}
+
+type yamlWriter struct {
+	dec *json.Decoder
+	w   *bufio.Writer
+}
+
+// node writes the value starting with tok, after its "key:" or "-" was written.
+// inline means a mapping can start on the same line, ex: "- name: x".
+func (y *yamlWriter) node(tok json.Token, indent int, inline bool) error {
+	switch tok {
+	case json.Delim('{'):
+		if !y.dec.More() {
+			y.w.WriteString(" {}\n")
+			_, err := y.dec.Token()
+			return err
+		}
+		if inline {
+			y.w.WriteByte(' ')
+		} else {
+			y.w.WriteByte('\n')
+		}
+		return y.mapping(indent, inline)
+
+	case json.Delim('['):
+		if !y.dec.More() {
+			y.w.WriteString(" []\n")
+			_, err := y.dec.Token()
+			return err
+		}
+		y.w.WriteByte('\n')
+		return y.sequence(indent)
+
+	default:
+		y.w.WriteString(" " + yamlScalar(tok) + "\n")
+		return nil
+	}
+}
+
+// mapping writes the keys of an object until its closing '}', skipIndent means the first key follows a "- ".
+func (y *yamlWriter) mapping(indent int, skipIndent bool) error {
+	for y.dec.More() {
+		tok, err := y.dec.Token()
+		if err != nil {
+			return err
+		}
+		if !skipIndent {
+			y.w.WriteString(strings.Repeat(" ", indent))
+		}
+		skipIndent = false
+		y.w.WriteString(yamlScalar(tok) + ":")
+
+		if tok, err = y.dec.Token(); err != nil {
+			return err
+		}
+		if err = y.node(tok, indent+2, false); err != nil {
+			return err
+		}
+	}
+	_, err := y.dec.Token()
+	return err
+}
+
+// sequence writes the items of an array until its closing ']'.
+func (y *yamlWriter) sequence(indent int) error {
+	for y.dec.More() {
+		tok, err := y.dec.Token()
+		if err != nil {
+			return err
+		}
+		y.w.WriteString(strings.Repeat(" ", indent) + "-")
+		if err = y.node(tok, indent+2, true); err != nil {
+			return err
+		}
+	}
+	_, err := y.dec.Token()
+	return err
+}
+
+var plainYAML = regexp.MustCompile(`^[A-Za-z_$/][A-Za-z0-9_$./{}<>() -]*$`)
+
+func yamlScalar(tok json.Token) string {
+	switch v := tok.(type) {
+	case nil:
+		return "null"
+	case string:
+		switch strings.ToLower(v) {
+		case "true", "false", "null", "yes", "no", "on", "off", "y", "n":
+			return strconv.Quote(v)
+		}
+		if plainYAML.MatchString(v) && !strings.HasSuffix(v, " ") {
+			return v
+		}
+		return strconv.Quote(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
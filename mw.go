@@ -2,9 +2,11 @@ package gserv
 
 import (
 	"bytes"
+	"cmp"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -50,14 +52,14 @@ func LogRequests(logJSONRequests bool) Handler {
 
 		ct := req.Header.Get("Content-Type")
 
-		switch ct {
-		case MimeJSON:
-			ct = "[JSON] "
-		case MimeMsgPack:
-			ct = "[MSGP] "
-		case MimeEvent:
+		switch c := CodecFor(ct); {
+		case c != nil && c.ContentType() != "":
+			// the subtype of the codec's content type, ex: [JSON] for application/json and application/problem+json
+			_, sub, _ := strings.Cut(mediaType(c.ContentType()), "/")
+			ct = "[" + strings.ToUpper(sub) + "] "
+		case mediaType(ct) == MimeEvent:
 			ct = "[SSE] "
-		case "":
+		case ct == "":
 		default:
 			ct = "[" + ct + "] "
 		}
@@ -87,3 +89,30 @@ func GetSecureCookie(ctx *Context) *securecookie.SecureCookie {
 	}
 	return nil
 }
+
+// ValidateRequests returns a middleware that validates the params, query, headers and JSON body of each request
+// against its route's OpenAPI documentation, see router.Route.Validate, and responds with a 400 GenResponse
+// with an Error per invalid field before the handler runs, or a 413 if the body is larger than the router's
+// MaxValidateBodySize, see SetRouterOptions. Undocumented routes aren't checked.
+func ValidateRequests() Handler {
+	return func(ctx *Context) Response {
+		rn := ctx.Route()
+		if rn == nil {
+			return nil
+		}
+		verrs := rn.Validate(ctx.Req, ctx.Params)
+		if len(verrs) == 0 {
+			return nil
+		}
+
+		code := http.StatusBadRequest
+		errs := make([]any, 0, len(verrs))
+		for _, ve := range verrs {
+			if ve.Status != 0 {
+				code = ve.Status
+			}
+			errs = append(errs, Error{Message: ve.Error(), Code: cmp.Or(ve.Status, http.StatusBadRequest), Field: ve.Field, In: ve.In})
+		}
+		return NewErrorResponse[JSONCodec](code, errs...)
+	}
+}
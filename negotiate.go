@@ -0,0 +1,135 @@
+package gserv
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrNotAcceptable is returned by Context.Negotiate when none of the registered codecs is acceptable.
+var ErrNotAcceptable = NewError(http.StatusNotAcceptable, "none of the accepted content types can be served")
+
+// Negotiate returns the registered codec the request's Accept header prefers, see RegisterCodec,
+// or def if the request doesn't have an Accept header, accepts def as much as the other codecs,
+// or def doesn't have a content type, ex: PlainTextCodec.
+//
+// Codecs are ranked by the q-value of the most specific media range matching their content type, ex: application/json
+// over application/* over */*, then by the order of those ranges in the header.
+// It returns def and ErrNotAcceptable (406) if none of them is acceptable.
+func (ctx *Context) Negotiate(def Codec) (Codec, error) {
+	defCT := ""
+	if def != nil {
+		defCT = mediaType(def.ContentType())
+	}
+	if defCT == "" || ctx.Req == nil {
+		return def, nil
+	}
+
+	addVary(ctx.Header(), "Accept")
+	accept := ctx.Req.Header.Values("Accept")
+	if len(accept) == 0 {
+		return def, nil
+	}
+
+	ranges := parseAccept(accept)
+	var (
+		best    Codec
+		bestQ   float64
+		bestIdx int
+	)
+	consider := func(ct string, c Codec) {
+		if q, idx := acceptQ(ranges, ct); q > bestQ || q > 0 && q == bestQ && idx < bestIdx {
+			best, bestQ, bestIdx = c, q, idx
+		}
+	}
+
+	consider(defCT, def)
+	codecs.RLock()
+	for _, ct := range codecs.types {
+		if ct != defCT {
+			consider(ct, codecs.m[ct])
+		}
+	}
+	codecs.RUnlock()
+
+	if best == nil {
+		return def, ErrNotAcceptable
+	}
+	return best, nil
+}
+
+type acceptRange struct {
+	typ, sub string
+	q        float64
+}
+
+// parseAccept parses the media ranges of Accept headers, ranges with an invalid q-value are ignored.
+func parseAccept(headers []string) []acceptRange {
+	var ranges []acceptRange
+	for _, h := range headers {
+		for part := range strings.SplitSeq(h, ",") {
+			mt, params, _ := strings.Cut(part, ";")
+			typ, sub, ok := strings.Cut(strings.ToLower(strings.TrimSpace(mt)), "/")
+			if !ok {
+				continue
+			}
+
+			ar := acceptRange{typ: typ, sub: sub, q: 1}
+			for p := range strings.SplitSeq(params, ";") {
+				k, v, _ := strings.Cut(p, "=")
+				if strings.TrimSpace(k) != "q" {
+					continue
+				}
+				q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if err != nil || q < 0 || q > 1 {
+					ar.q = -1
+				} else {
+					ar.q = q
+				}
+			}
+			if ar.q >= 0 {
+				ranges = append(ranges, ar)
+			}
+		}
+	}
+	return ranges
+}
+
+// acceptQ returns the q-value of the most specific range matching the media type ct and its index, q is 0 if none does.
+func acceptQ(ranges []acceptRange, ct string) (q float64, idx int) {
+	typ, sub, _ := strings.Cut(ct, "/")
+	specificity := 0
+	for i, r := range ranges {
+		s := 0
+		switch {
+		case r.typ == typ && r.sub == sub:
+			s = 3
+		case r.typ == typ && r.sub == "*":
+			s = 2
+		case r.typ == "*" && r.sub == "*":
+			s = 1
+		}
+		if s > specificity {
+			specificity, q, idx = s, r.q, i
+		}
+	}
+	return q, idx
+}
+
+// addVary adds field to the Vary header unless it's already listed, ex: by a previous call to Negotiate.
+func addVary(h http.Header, field string) {
+	for _, v := range h.Values("Vary") {
+		for f := range strings.SplitSeq(v, ",") {
+			if f = strings.TrimSpace(f); f == "*" || strings.EqualFold(f, field) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", field)
+}
+
+// mediaType returns the lower cased media type of a content type, without its params.
+func mediaType(ct string) string {
+	mt, _, _ := strings.Cut(ct, ";")
+	return strings.ToLower(strings.TrimSpace(mt))
+}
@@ -0,0 +1,94 @@
+package gserv
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"go.oneofone.dev/gserv/internal"
+)
+
+// MimeYAML is the content type ServeOpenAPI uses for YAML documents.
+const MimeYAML = "application/yaml"
+
+//go:embed openapi.html
+var openAPIPage []byte
+
+// ServeOpenAPI registers a GET route at path that serves the OpenAPI document of the group's router as JSON,
+// or as YAML if the request prefers application/yaml (or text/yaml) to JSON, by q-value then order, or has ?format=yaml.
+// Only the routes documented as public are listed, see router.SwaggerRoute.AsPublic.
+//
+// If withUI is set, browsers (Accept: text/html) or ?format=html get a docs page embedded in the binary,
+// so it works offline, ex:
+//
+//	srv.ServeOpenAPI("/openapi", true, authMiddleware)
+func (g *Group) ServeOpenAPI(path string, withUI bool, mw ...Handler) Route {
+	return g.AddRoute(http.MethodGet, path, append(mw[:len(mw):len(mw)], func(ctx *Context) Response {
+		format := ctx.Query("format")
+		if format == "" {
+			addVary(ctx.Header(), "Accept")
+			format = openAPIFormat(ctx.Req.Header.Values("Accept"), withUI)
+		}
+
+		switch format {
+		case "html":
+			if !withUI {
+				return RespNotFound
+			}
+			ctx.SetContentType(MimeHTML + "; charset=utf-8")
+			_, _ = ctx.Write(openAPIPage)
+			return nil
+
+		case "yaml":
+			j, err := json.Marshal(g.r.PublicSwagger())
+			if err != nil {
+				return NewJSONErrorResponse(http.StatusInternalServerError, err)
+			}
+			var buf bytes.Buffer
+			if err := internal.JSONToYAML(&buf, j); err != nil {
+				return NewJSONErrorResponse(http.StatusInternalServerError, err)
+			}
+			ctx.SetContentType(MimeYAML)
+			_, _ = ctx.Write(buf.Bytes())
+			return nil
+
+		default:
+			j, err := json.Marshal(g.r.PublicSwagger())
+			if err != nil {
+				return NewJSONErrorResponse(http.StatusInternalServerError, err)
+			}
+			ctx.SetContentType(MimeJSON)
+			_, _ = ctx.Write(append(j, '\n'))
+			return nil
+		}
+	})...)
+}
+
+// openAPIFormat returns the format the Accept headers prefer among those ServeOpenAPI can serve, like Context.Negotiate,
+// json by default.
+func openAPIFormat(accept []string, withUI bool) string {
+	if len(accept) == 0 {
+		return "json"
+	}
+
+	ranges := parseAccept(accept)
+	var (
+		best    = "json"
+		bestQ   float64
+		bestIdx int
+	)
+	consider := func(format string, types ...string) {
+		for _, ct := range types {
+			if q, idx := acceptQ(ranges, ct); q > bestQ || q > 0 && q == bestQ && idx < bestIdx {
+				best, bestQ, bestIdx = format, q, idx
+			}
+		}
+	}
+	consider("json", MimeJSON)
+	consider("yaml", MimeYAML, "application/x-yaml", "text/yaml")
+	if withUI {
+		consider("html", MimeHTML)
+	}
+	return best
+}
@@ -76,3 +76,30 @@ func SetOnReqDone(fn router.OnRequestDone) Option {
 		opt.RouterOptions.OnRequestDone = fn
 	}
 }
+
+// SetAutoOptions makes the router answer OPTIONS requests on any known path with an Allow header computed from the routes,
+// if cors isn't nil, it's used to answer CORS preflight requests as well, replacing per-group AllowCORS routes.
+func SetAutoOptions(cors *router.CORS) Option {
+	return func(opt *Options) {
+		ro := router.Options{}
+		if opt.RouterOptions != nil {
+			ro = *opt.RouterOptions
+		}
+		ro.AutoOptions, ro.CORS = true, cors
+		opt.RouterOptions = &ro
+	}
+}
+
+// SetRedirects makes the router redirect to the canonical path of a route instead of returning 404,
+// for a trailing slash mismatch (ex: /users/ -> /users) and/or a case mismatch (ex: /Users -> /users).
+// GET and HEAD requests get a 301, other methods a 308 so they keep their body.
+func SetRedirects(trailingSlash, caseInsensitive bool) Option {
+	return func(opt *Options) {
+		ro := router.Options{}
+		if opt.RouterOptions != nil {
+			ro = *opt.RouterOptions
+		}
+		ro.RedirectTrailingSlash, ro.RedirectCaseInsensitive = trailingSlash, caseInsensitive
+		opt.RouterOptions = &ro
+	}
+}
@@ -16,5 +16,5 @@ func TestBugGithub3(t *testing.T) {
 	if rn == nil {
 		t.Fatal("couldn't find the handler")
 	}
-	rn.h(nil, nil, p)
+	rn.Handler()(nil, nil, p)
 }
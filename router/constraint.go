@@ -0,0 +1,90 @@
+package router
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// constraint restricts the values a :param can match, ex: /users/:id<int> or /posts/:slug<[a-z0-9-]+>.
+type constraint struct {
+	name  string // as written in the pattern
+	match func(v string) bool
+	re    *regexp.Regexp
+}
+
+// builtinConstraints are the named constraints, anything else is compiled as a regexp.
+var builtinConstraints = map[string]func(v string) bool{
+	"int": func(v string) bool {
+		if len(v) > 1 && (v[0] == '-' || v[0] == '+') {
+			v = v[1:]
+		}
+		return isDigits(v)
+	},
+	"uint": isDigits,
+	"alpha": func(v string) bool {
+		for i := 0; i < len(v); i++ {
+			if c := v[i] | 0x20; c < 'a' || c > 'z' {
+				return false
+			}
+		}
+		return v != ""
+	},
+	"uuid": func(v string) bool {
+		if len(v) != 36 {
+			return false
+		}
+		for i := 0; i < len(v); i++ {
+			switch i {
+			case 8, 13, 18, 23:
+				if v[i] != '-' {
+					return false
+				}
+			default:
+				if !isHex(v[i]) {
+					return false
+				}
+			}
+		}
+		return true
+	},
+}
+
+func newConstraint(name string) (*constraint, error) {
+	if fn := builtinConstraints[name]; fn != nil {
+		return &constraint{name: name, match: fn}, nil
+	}
+
+	re, err := regexp.Compile("^(?:" + name + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid param constraint <%s>: %w", name, err)
+	}
+	return &constraint{name: name, match: re.MatchString, re: re}, nil
+}
+
+// schema returns the OpenAPI type, format and pattern of the constraint.
+func (c *constraint) schema() (typ, format, pattern string) {
+	switch c.name {
+	case "int":
+		return "integer", "int64", ""
+	case "uint":
+		return "integer", "uint64", ""
+	case "alpha":
+		return "string", "", "^[a-zA-Z]+$"
+	case "uuid":
+		return "string", "uuid", ""
+	}
+	return "string", "", c.re.String()
+}
+
+func isDigits(v string) bool {
+	for i := 0; i < len(v); i++ {
+		if v[i] < '0' || v[i] > '9' {
+			return false
+		}
+	}
+	return v != ""
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c|0x20 >= 'a' && c|0x20 <= 'f'
+}
@@ -0,0 +1,93 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS is a preflight policy used by Options.AutoOptions, the allowed methods are always computed from the routes.
+type CORS struct {
+	// Origins is the list of allowed origins, supports wildcard subdomains (ex: *.example.com),
+	// if empty any origin is allowed.
+	Origins []string
+
+	// Headers is the list of allowed headers, if empty the requested Access-Control-Request-Headers are allowed.
+	Headers []string
+
+	// MaxAge is how long the preflight response can be cached, defaults to 24 hours.
+	MaxAge time.Duration
+
+	// NoCredentials disables sending Access-Control-Allow-Credentials.
+	NoCredentials bool
+}
+
+// Allowed reports whether the origin is allowed by the policy.
+func (c *CORS) Allowed(origin string) bool {
+	if len(c.Origins) == 0 {
+		return true
+	}
+
+	origin = trimScheme(origin)
+	for _, o := range c.Origins {
+		o = trimScheme(o)
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+
+		if sfx, ok := strings.CutPrefix(o, "*."); ok && len(origin) > len(sfx)+1 &&
+			strings.HasSuffix(origin, sfx) && origin[len(origin)-len(sfx)-1] == '.' {
+			return true
+		}
+	}
+	return false
+}
+
+// apply sets the preflight headers if the request is a CORS preflight from an allowed origin.
+func (c *CORS) apply(w http.ResponseWriter, req *http.Request, allowed []string) {
+	rh, wh := req.Header, w.Header()
+	origin := rh.Get("Origin")
+	if origin == "" || rh.Get("Access-Control-Request-Method") == "" || !c.Allowed(origin) {
+		return
+	}
+
+	wh.Add("Vary", "Origin")
+	wh.Set("Access-Control-Allow-Origin", origin)
+	if !c.NoCredentials {
+		wh.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	wh.Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+
+	if len(c.Headers) > 0 {
+		wh.Set("Access-Control-Allow-Headers", strings.Join(c.Headers, ", "))
+	} else if h := rh.Get("Access-Control-Request-Headers"); h != "" {
+		wh.Set("Access-Control-Allow-Headers", h)
+	}
+
+	maxAge := c.MaxAge
+	if maxAge == 0 {
+		maxAge = 24 * time.Hour
+	}
+	wh.Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+}
+
+func (r *Router) serveOptions(w http.ResponseWriter, req *http.Request, allowed []string) {
+	if r.opts.CORS != nil {
+		r.opts.CORS.apply(w, req, allowed)
+	}
+
+	if r.OptionsHandler != nil {
+		r.OptionsHandler(w, req, nil)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func trimScheme(origin string) string {
+	if _, rest, ok := strings.Cut(origin, "://"); ok {
+		return rest
+	}
+	return origin
+}
@@ -20,9 +20,56 @@
 //   - *name — multi-segment wildcard parameter, must be the last segment
 //     (e.g. /files/*path matches /files/a/b/c.txt with path="a/b/c.txt")
 //
+// A :param can be constrained, a segment that doesn't satisfy the constraint falls
+// through to other routes (or 404), the constraint is also reflected in the swagger schema:
+//
+//   - :id<int>, :id<uint> — base 10 integers, see Params.GetInt and Params.GetUint
+//   - :name<alpha> — ASCII letters only
+//   - :id<uuid> — a canonical 8-4-4-4-12 hex UUID
+//   - :slug<[a-z0-9-]+> — any other constraint is a regexp that must match the whole segment
+//
+// A segment can hold multiple params as long as they're separated by a literal, ex: /files/:name.:ext
+// matches /files/report.tar.gz with name="report.tar" and ext="gz", the last param gets the shortest value,
+// params.GetExt is an alternative for a single :file param.
+//
+// Trailing params can be optional, ex: /posts/:year/:month? matches both /posts/2024 and /posts/2024/05,
+// a missing optional param is simply not in Params, so Get returns "". Optional params must be at the end of the path.
+//
 // Only one wildcard (*) parameter is allowed per route. It must appear as the
 // final path component.
 //
+// Routes are stored in a compressed radix tree per method, when multiple routes
+// can match a path the most specific one wins, static > :param<constraint> > :param > *wildcard,
+// checked segment by segment with backtracking, registration order doesn't matter:
+//
+//	/users/me   beats /users/:id for /users/me
+//	/users/:id  beats /users/*rest for /users/42
+//	/a/:x/c     matches /a/b/c even if /a/b/d exists
+//
+// Routes of the same method that would match the same paths conflict, AddRoute panics with a *ConflictError
+// naming the existing route, ex: /users/:name after /users/:id, or /posts/:year after /posts/:year/:month?.
+// Params with different constraints never conflict, even if they overlap (ex: :id<int> and :id<uint>),
+// the one registered first wins the values both match.
+//
+// # Methods
+//
+// Any RFC 9110 token is a valid method (ex: PROPFIND, QUERY or a custom verb), the
+// standard methods are looked up in a fixed table, anything else goes through a map.
+// AddRoute panics on an invalid method. Methods are case-sensitive.
+//
+// AddRouteE returns the problem with a route instead of panicking, a *RouteError for an invalid method or pattern,
+// which wraps one of ErrInvalidMethod, ErrInvalidPattern, ErrTooManyStars, ErrStarNotLast, ErrEmptyParamName
+// or ErrDuplicateParamName, or a *ConflictError:
+//
+//	if _, err := r.AddRouteE("users", cfg.Method, cfg.Path, h); err != nil {
+//		log.Printf("skipping route: %v", err)
+//	}
+//
+// MethodAny routes match any method that doesn't have its own route for the path, Router.Mount uses it
+// to hand everything under a prefix to another handler:
+//
+//	r.Mount("admin", "/admin", adminHandler) // /admin/users/1 -> mountPath="users/1"
+//
 // # Options
 //
 // The router accepts an optional *router.Options struct:
@@ -32,12 +79,18 @@
 //   - APIInfo — OpenAPI info object (title, description, version).
 //   - NoAutoCleanURL — skip automatic URL path cleaning (e.g. /a/../b → /b).
 //   - NoDefaultPanicHandler — do not set the default panic recovery handler.
-//   - NoPanicOnInvalidAddRoute — return an error instead of panicking when
-//     AddRoute receives an invalid route pattern.
+//   - NoPanicOnInvalidAddRoute — return nil instead of panicking when
+//     AddRoute receives an invalid or conflicting route, see AddRouteE to get the error.
 //   - CatchPanics — enable panic recovery (on by default).
 //   - NoAutoHeadToGet — disable automatic HEAD → GET fallback.
 //   - ProfileLabels — add pprof labels (group, method, uri) to the goroutine context.
 //   - AutoGenerateSwagger — automatically generate OpenAPI documentation for routes.
+//   - AutoOptions — answer OPTIONS requests on known paths with an Allow header computed
+//     from the routes, and CORS preflight headers if a CORS policy is set.
+//   - RedirectTrailingSlash — redirect /users/ to /users if only the latter has a route.
+//   - RedirectCaseInsensitive — redirect /Users/42 to /users/42, the route matched ignoring the case of its static parts.
+//     Both redirects use 301 for GET and HEAD and 308 for other methods, and only kick in after an exact match failed.
+//   - MaxValidateBodySize — the max size of the bodies Route.Validate reads, 10MB by default.
 //
 // # Groups
 //
@@ -55,11 +108,34 @@
 //
 // Route parameters are provided as a router.Params slice, keyed by parameter name.
 // The Params type is NOT safe for use outside the handler — if you need to store
-// it, call params.Copy(). Two helper methods are available:
+// it, call params.Copy(). A few helper methods are available:
 //
 //   - Get(name) — retrieve a parameter value by name.
+//   - GetInt(name), GetUint(name) — parse a parameter as a base 10 integer.
 //   - GetExt(name) — split the parameter at its last extension (e.g. "report.json" → ("report", "json")).
 //
+// # Route Middleware and Metadata
+//
+// Middleware can be added to a single route, and typed metadata attached to it, keyed by the value's type,
+// which any handler or middleware can read back from the matched route:
+//
+//	type Scopes []string
+//	rn := r.AddRoute("users", "DELETE", "/users/:id", handler).Use(requireAuth)
+//	router.SetMeta(rn, Scopes{"users:write"})
+//
+//	scopes, ok := router.Meta[Scopes](router.RouteFromRequest(req))
+//
+// # Named Routes
+//
+// A route can be named and its path rebuilt from its pattern, with escaped values:
+//
+//	r.AddRoute("users", "GET", "/users/:id", handler).Name("user.show")
+//	u, err := r.URL("user.show", "id", "42") // "/users/42"
+//
+// Params are key/value pairs, a missing required param returns ErrMissingParam,
+// a value that doesn't satisfy its constraint, or a :param value with a slash, returns ErrInvalidParam.
+// Name panics if the name is used by another route, unless NoPanicOnInvalidAddRoute is set, see NameE to get the error.
+//
 // # Swagger/OpenAPI Support
 //
 // Routes can be documented using the WithDoc method:
@@ -71,22 +147,56 @@
 //
 //   - WithOperationID, WithSummary, WithDescription, WithTags
 //   - WithParam(name, desc, in, typ, required, schema) — add a parameter
+//   - WithParamSchema(name, in, schema) — set the schema of a documented parameter
 //   - WithBody(contentType, example) — document request body
+//   - WithBodySchema(contentType, schema) — document the request body's schema
 //   - WithResponse(statusCode, description) — document response
+//   - WithResponseSchema(status, description, contentType, schema) — document a response's schema
 //   - WithExample(name, desc) — add an example
 //   - AsPublic() — mark route documentation as public
 //
-// The full OpenAPI spec can be retrieved via router.Swagger().
+// Router.SchemaOf reflects a Go type into a JSON schema following encoding/json, named structs are added
+// to components/schemas and referenced, and validate tags, ex: `validate:"required,max=64"`, add their constraints,
+// only fields with the required rule are required.
+// Route.Doc returns the route's documentation to attach them to:
+//
+//	rn.Doc().WithBodySchema("application/json", r.SchemaOf(reflect.TypeFor[User]()))
+//
+// Route.Validate checks a request's params, query, headers and JSON body against the route's documentation,
+// returning a ValidationError per invalid value.
+//
+// Once a route is documented, the builders replace its documentation with an updated copy and return it,
+// so routes can be documented while serving requests without changing the documentation Validate is using.
 //
-// # Disabling Routes
+// The full OpenAPI 3.1 spec can be retrieved via router.Swagger(), route patterns are marshaled as OpenAPI paths,
+// ex: /users/:id<int>/:tab? is listed as /users/{id} and /users/{id}/{tab}.
+//
+// # Disabling and Removing Routes
 //
 // Individual routes can be disabled at runtime without removing them:
 //
 //	r.DisableRoute("GET", "/users/:id", true)
 //
+// A disabled route is skipped by lookups, so it doesn't shadow other routes (ex: disabling /users/me
+// makes /users/:id match /users/me), and it's not listed by GetRoutes.
+//
+// Or removed by their exact pattern, which also removes their swagger documentation:
+//
+//	r.RemoveRoute("GET", "/users/:id")
+//
+// A route's handler can be replaced with Route.SetHandler, see Router.Route to get a registered route.
+//
+// AddRoute, DisableRoute and RemoveRoute are safe to call while serving requests, the routes are an
+// immutable snapshot that lookups load atomically and changes replace with an updated copy,
+// so lookups never lock and in-flight requests finish on the routes they started with.
+//
 // # Method Fallback
 //
 // By default, if no handler is registered for HEAD requests on a path, the router
 // automatically falls back to the GET handler for that path and discards the body.
+//
+// If a path has no route for the request's method but has routes for other methods,
+// the router responds with 405 and an Allow header listing them (see Router.Allowed),
+// the list is also available to MethodNotAllowedHandler via AllowedFromRequest.
 
 package router
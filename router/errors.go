@@ -0,0 +1,64 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidMethod is returned by AddRouteE if the method isn't a valid RFC 9110 token.
+	ErrInvalidMethod = errors.New("router: invalid method")
+	// ErrInvalidPattern is returned by AddRouteE if the pattern can't be parsed, ex: an invalid constraint or an optional param that isn't last.
+	ErrInvalidPattern = errors.New("router: invalid route pattern")
+	// ErrTooManyStars is returned by AddRouteE if the pattern has more than one *param.
+	ErrTooManyStars = errors.New("router: too many star params")
+	// ErrStarNotLast is returned by AddRouteE if a *param isn't the last part of the pattern.
+	ErrStarNotLast = errors.New("router: star param must be the last part of the path")
+	// ErrEmptyParamName is returned by AddRouteE if a :param or *param has no name.
+	ErrEmptyParamName = errors.New("router: empty param name")
+	// ErrDuplicateParamName is returned by AddRouteE if two params of the pattern have the same name.
+	ErrDuplicateParamName = errors.New("router: duplicate param name")
+
+	// ErrRouteConflict is wrapped by ConflictError.
+	ErrRouteConflict = errors.New("router: route conflict")
+)
+
+// RouteError is returned by AddRouteE, and panicked by AddRoute, if a route's method or pattern is invalid,
+// Err is one of the ErrXxx values above and can be checked with errors.Is.
+type RouteError struct {
+	Method  string
+	Pattern string
+	Param   string // the param at fault, if any
+	Err     error
+}
+
+func (e *RouteError) Error() string {
+	s := fmt.Sprintf("%v: %s %s", e.Err, e.Method, e.Pattern)
+	if e.Param != "" {
+		s += ", param " + e.Param
+	}
+	return s
+}
+
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// ConflictError is returned by AddRouteE, and panicked by AddRoute, if the route would match the same requests
+// as an existing route of the same method, ex: /users/:id and /users/:name, or the same pattern added twice.
+// The existing route keeps serving them.
+// Only params with the same constraint, or none, conflict, different constraints that overlap, ex: /n/:id<int>
+// and /n/:id<uint>, or :id<int> and :id<[0-9a-f]+>, aren't detected, the one registered first wins the values both match.
+type ConflictError struct {
+	Method   string
+	Pattern  string
+	Existing *Route
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("router: %s %s conflicts with %s %s (group %q)", e.Method, e.Pattern, e.Existing.m, e.Existing.fp, e.Existing.g)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrRouteConflict
+}
@@ -0,0 +1,208 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo describes a registered route, see Router.Routes.
+type RouteInfo struct {
+	Method     string   `json:"method"`
+	Pattern    string   `json:"pattern"`
+	Group      string   `json:"group,omitempty"`
+	Name       string   `json:"name,omitempty"`
+	Handlers   []string `json:"handlers,omitempty"`
+	Middleware []string `json:"middleware,omitempty"`
+	Disabled   bool     `json:"disabled,omitempty"`
+	HasDoc     bool     `json:"hasDoc,omitempty"`
+}
+
+// ChainInfo can be attached to a route with SetMeta by handlers that wrap a chain of other handlers,
+// so RouteInfo lists the wrapped handlers and middleware instead of the wrapper.
+type ChainInfo struct {
+	Handlers   []string
+	Middleware []string
+}
+
+// Routes returns a description of every registered route, disabled ones included, sorted by pattern then method.
+func (r *Router) Routes() []RouteInfo {
+	tbl := r.tbl.Load()
+	names := make(map[*Route]string, len(tbl.names))
+	for name, rn := range tbl.names {
+		names[rn] = name
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	out := make([]RouteInfo, 0, len(tbl.routes))
+	for _, rn := range tbl.routes {
+		ri := RouteInfo{
+			Method:   rn.m,
+			Pattern:  rn.fp,
+			Group:    rn.g,
+			Name:     names[rn],
+			Disabled: rn.disabled.Load(),
+			HasDoc:   r.hasRouteInfo(rn.m, rn.fp),
+		}
+
+		if ci, ok := Meta[ChainInfo](rn); ok {
+			ri.Handlers, ri.Middleware = ci.Handlers, append([]string(nil), ci.Middleware...)
+		} else {
+			ri.Handlers = []string{FuncName(*rn.h.Load())}
+		}
+		for _, mw := range rn.mw {
+			ri.Middleware = append(ri.Middleware, FuncName(mw))
+		}
+
+		out = append(out, ri)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if a, b := out[i].Pattern, out[j].Pattern; a != b {
+			return a < b
+		}
+		return methodOrder(out[i].Method) < methodOrder(out[j].Method)
+	})
+	return out
+}
+
+// methodOrder sorts the standard methods in stdMethods order, then everything else.
+func methodOrder(method string) int {
+	if i := methodIndex(method); i > -1 {
+		return i
+	}
+	return len(stdMethods)
+}
+
+// FuncName returns the name of the function fn without its package path, ex: gserv.LogRequests.func1, or "<nil>".
+func FuncName(fn any) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return "<nil>"
+	}
+
+	f := runtime.FuncForPC(v.Pointer())
+	if f == nil {
+		return "<unknown>"
+	}
+
+	name := f.Name()
+	if i := strings.LastIndexByte(name, '/'); i > -1 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, "-fm")
+}
+
+// DebugHandler returns a handler that renders Routes as JSON if the request has ?format=json
+// or accepts application/json, otherwise as an aligned text tree, see WriteRoutesTree.
+func (r *Router) DebugHandler() Handler {
+	return func(w http.ResponseWriter, req *http.Request, _ Params) {
+		routes := r.Routes()
+		if req.URL.Query().Get("format") == "json" || strings.Contains(req.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "\t")
+			_ = enc.Encode(routes)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_ = WriteRoutesTree(w, routes)
+	}
+}
+
+// WriteRoutesTree writes routes as a tree of their path segments, with aligned columns, ex:
+//
+//	/
+//	└── users                 GET     users  main.listUsers
+//	    ├── :id               GET     users  main.getUser      mw: gserv.LogRequests.func1
+//	    │                     DELETE  users  main.deleteUser   disabled
+//	    └── me                GET     users  main.me           doc
+func WriteRoutesTree(w io.Writer, routes []RouteInfo) error {
+	type treeNode struct {
+		name     string
+		routes   []RouteInfo
+		children []*treeNode
+	}
+
+	root := &treeNode{name: "/"}
+	for _, ri := range routes {
+		n := root
+		for _, part := range strings.Split(strings.Trim(ri.Pattern, "/"), "/") {
+			if part == "" {
+				continue
+			}
+			var c *treeNode
+			for _, cc := range n.children {
+				if cc.name == part {
+					c = cc
+					break
+				}
+			}
+			if c == nil {
+				c = &treeNode{name: part}
+				n.children = append(n.children, c)
+			}
+			n = c
+		}
+		n.routes = append(n.routes, ri)
+	}
+
+	var buf strings.Builder
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+
+	var walk func(n *treeNode, label, cont string)
+	walk = func(n *treeNode, label, cont string) {
+		if len(n.routes) == 0 {
+			fmt.Fprintf(tw, "%s\t\t\t\t\n", label)
+		}
+		for i, ri := range n.routes {
+			if i > 0 {
+				label = cont
+			}
+			var extra []string
+			if len(ri.Middleware) > 0 {
+				extra = append(extra, "mw: "+strings.Join(ri.Middleware, ", "))
+			}
+			if ri.Name != "" {
+				extra = append(extra, "name: "+ri.Name)
+			}
+			if ri.HasDoc {
+				extra = append(extra, "doc")
+			}
+			if ri.Disabled {
+				extra = append(extra, "disabled")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", label, ri.Method, ri.Group, strings.Join(ri.Handlers, ", "), strings.Join(extra, "  "))
+		}
+
+		for i, c := range n.children {
+			branch, next := "├── ", "│   "
+			if i == len(n.children)-1 {
+				branch, next = "└── ", "    "
+			}
+			walk(c, cont+branch+c.name, cont+next)
+		}
+	}
+	walk(root, "/", "")
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// empty trailing cells are still padded
+	lines := strings.SplitAfter(buf.String(), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \n")
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n"))
+	return err
+}
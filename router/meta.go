@@ -0,0 +1,40 @@
+package router
+
+import "maps"
+
+// metaKey is the key of a metadata value of type T, so each type gets its own slot.
+type metaKey[T any] struct{}
+
+// SetMeta attaches v to the route, keyed by its type, replacing any previous value of the same type, ex:
+//
+//	type Scopes []string
+//	router.SetMeta(r.AddRoute("", "DELETE", "/users/:id", h), Scopes{"users:write"})
+//
+// It's safe to call while serving requests.
+func SetMeta[T any](r *Route, v T) *Route {
+	r.r.mux.Lock()
+	defer r.r.mux.Unlock()
+
+	var m map[any]any
+	if old := r.meta.Load(); old != nil {
+		m = maps.Clone(*old)
+	} else {
+		m = map[any]any{}
+	}
+	m[metaKey[T]{}] = v
+	r.meta.Store(&m)
+	return r
+}
+
+// Meta returns the route's metadata value of type T, r can be nil, ex:
+//
+//	if scopes, ok := router.Meta[Scopes](router.RouteFromRequest(req)); ok { ... }
+func Meta[T any](r *Route) (v T, ok bool) {
+	if r == nil {
+		return v, false
+	}
+	if m := r.meta.Load(); m != nil {
+		v, ok = (*m)[metaKey[T]{}].(T)
+	}
+	return v, ok
+}
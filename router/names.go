@@ -0,0 +1,138 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrUnknownRoute is returned by URL if there's no route with the given name.
+	ErrUnknownRoute = errors.New("router: unknown route name")
+	// ErrMissingParam is returned by URL if a required param of the route isn't provided.
+	ErrMissingParam = errors.New("router: missing route param")
+	// ErrInvalidParam is returned by URL if a param doesn't satisfy its constraint, a :param has a slash,
+	// or the params aren't key/value pairs.
+	ErrInvalidParam = errors.New("router: invalid route param")
+	// ErrDuplicateName is returned by NameE if the name is used by another route.
+	ErrDuplicateName = errors.New("router: duplicate route name")
+)
+
+// Name names the route so its URL can be built with Router.URL, names are unique per router.
+// It panics with the error NameE would return, or keeps the existing name if Options.NoPanicOnInvalidAddRoute is set.
+func (r *Route) Name(name string) *Route {
+	if err := r.NameE(name); err != nil && !r.r.opts.NoPanicOnInvalidAddRoute {
+		panic(err)
+	}
+	return r
+}
+
+// NameE is like Name, but returns an error wrapping ErrDuplicateName if the name is used by another route
+// instead of panicking. Naming a removed route is a no-op.
+func (r *Route) NameE(name string) (err error) {
+	r.r.update(func(tbl *table) bool {
+		if tbl.route(r.m, r.fp) != r {
+			return false
+		}
+		if ex := tbl.names[name]; ex == r {
+			return false
+		} else if ex != nil {
+			err = fmt.Errorf("%w: %s is used by %s %s", ErrDuplicateName, name, ex.m, ex.fp)
+			return false
+		}
+		tbl.names = maps.Clone(tbl.names)
+		if tbl.names == nil {
+			tbl.names = map[string]*Route{}
+		}
+		tbl.names[name] = r
+		return true
+	})
+	return err
+}
+
+// Named returns the route with the given name, or nil.
+func (r *Router) Named(name string) *Route {
+	return r.tbl.Load().names[name]
+}
+
+// URL builds the path of the route with the given name, params are key/value pairs, ex:
+//
+//	r.AddRoute("", "GET", "/users/:id/files/*fp", h).Name("user.file")
+//	r.URL("user.file", "id", "42", "fp", "a b/c.txt") // /users/42/files/a%20b/c.txt
+//
+// Param values are escaped, a *star value keeps its slashes and can be empty or omitted, a :param value can't have a slash
+// since it would be matched as two segments, optional params can be omitted,
+// and a missing required param or one that doesn't satisfy its constraint is an error.
+func (r *Router) URL(name string, params ...string) (string, error) {
+	rn := r.Named(name)
+	if rn == nil {
+		return "", fmt.Errorf("%w: %s", ErrUnknownRoute, name)
+	}
+	return rn.URL(params...)
+}
+
+// URL builds the route's path with the given params, see Router.URL.
+func (r *Route) URL(params ...string) (string, error) {
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("%w: odd number of key/value params for %s", ErrInvalidParam, r.fp)
+	}
+
+	get := func(name string) (string, bool) {
+		for i := 0; i < len(params); i += 2 {
+			if params[i] == name {
+				return params[i+1], true
+			}
+		}
+		return "", false
+	}
+
+	variants, _ := expandOptional(r.segs)
+	var (
+		sb      strings.Builder
+		missing string
+	)
+
+next:
+	for _, segs := range variants {
+		sb.Reset()
+		for _, s := range segs {
+			if s.kind == segStatic {
+				sb.WriteString(s.val)
+				continue
+			}
+
+			// a catch-all can be empty, ex: /files/*fp matches /files/
+			v, ok := get(s.val)
+			if !ok && s.kind != segStar {
+				if missing == "" {
+					missing = s.val
+				}
+				// try the variant without the trailing optional params
+				continue next
+			}
+
+			if s.kind == segStar {
+				parts := strings.Split(v, "/")
+				for i, p := range parts {
+					parts[i] = url.PathEscape(p)
+				}
+				sb.WriteString(strings.Join(parts, "/"))
+				continue
+			}
+
+			if v == "" || strings.IndexByte(v, '/') != -1 || s.cons != nil && !s.cons.match(v) {
+				return "", fmt.Errorf("%w: %s=%q for %s", ErrInvalidParam, s.val, v, r.fp)
+			}
+			sb.WriteString(url.PathEscape(v))
+		}
+
+		if sb.Len() == 0 {
+			return "/", nil
+		}
+		return sb.String(), nil
+	}
+
+	return "", fmt.Errorf("%w: %s for %s", ErrMissingParam, missing, r.fp)
+}
@@ -1,5 +1,7 @@
 package router
 
+import "strconv"
+
 // Param is a key/value pair
 type Param struct {
 	Name  string
@@ -19,7 +21,18 @@ func (p Params) Get(name string) string {
 	return ""
 }
 
+// GetInt returns a param parsed as a base 10 int64, ex: /users/:id<int>.
+func (p Params) GetInt(name string) (int64, error) {
+	return strconv.ParseInt(p.Get(name), 10, 64)
+}
+
+// GetUint returns a param parsed as a base 10 uint64, ex: /users/:id<uint>.
+func (p Params) GetUint(name string) (uint64, error) {
+	return strconv.ParseUint(p.Get(name), 10, 64)
+}
+
 // GetExt returns the value split at the last extension available, for example:
+//
 //	if :filename == "report.json", GetExt("filename") returns "report", "json"
 func (p Params) GetExt(name string) (val, ext string) {
 	val = p.Get(name)
@@ -5,7 +5,9 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"runtime/pprof"
+	"strings"
 	"time"
 )
 
@@ -13,6 +15,9 @@ import (
 // *note* `p` is NOT safe to be used outside the handler, call p.Copy() if you need to use it.
 type Handler = func(w http.ResponseWriter, req *http.Request, p Params)
 
+// Middleware wraps a Handler, see Route.Use.
+type Middleware = func(next Handler) Handler
+
 // PanicHandler is a special handler that gets called if a panic happens
 type PanicHandler = func(w http.ResponseWriter, req *http.Request, v any)
 
@@ -21,7 +26,7 @@ func DefaultPanicHandler(w http.ResponseWriter, req *http.Request, v any) {
 	http.Error(w, fmt.Sprintf("panic (%T): %v", v, v), http.StatusInternalServerError)
 }
 
-// DefaultNotFoundHandler is the default panic handler
+// DefaultNotFoundHandler is the default not found handler
 func DefaultNotFoundHandler(w http.ResponseWriter, req *http.Request, _ Params) {
 	http.Error(w, "404 page not found", http.StatusNotFound)
 }
@@ -51,7 +56,7 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		w, method = &headRW{ResponseWriter: w}, http.MethodGet
 	}
 
-	if rn, p := r.match(method, pathNoQuery(u)); rn != nil && !rn.disabled.Load() {
+	if rn, p := r.match(method, pathNoQuery(u)); rn != nil {
 		if r.opts.ProfileLabels {
 			labels := pprof.Labels("group", rn.g, "method", req.Method, "uri", req.RequestURI)
 			ctx := pprof.WithLabels(req.Context(), labels)
@@ -59,7 +64,7 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 			req = req.WithContext(ctx)
 		}
 		req = req.WithContext(context.WithValue(req.Context(), routeCtxKey, rn))
-		rn.h(w, req, p.Params())
+		(*rn.serve.Load())(w, req, p.Params())
 		r.putParams(p)
 
 		if r.opts.OnRequestDone != nil {
@@ -69,17 +74,76 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if method == http.MethodGet {
-		if r.NotFoundHandler != nil {
-			r.NotFoundHandler(w, req, nil)
-		} else {
-			w.WriteHeader(http.StatusNotFound)
+	if r.opts.RedirectTrailingSlash || r.opts.RedirectCaseInsensitive {
+		if fixed := r.fixPath(method, pathNoQuery(u)); fixed != "" {
+			code := http.StatusMovedPermanently
+			if method != http.MethodGet {
+				// keep the method and body
+				code = http.StatusPermanentRedirect
+			}
+			loc := url.URL{Path: fixed, RawQuery: req.URL.RawQuery}
+			http.Redirect(w, req, loc.String(), code)
+			return
 		}
-	} else {
+	}
+
+	if allowed := r.Allowed(pathNoQuery(u)); len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		req = req.WithContext(context.WithValue(req.Context(), allowedCtxKey, allowed))
+		if method == http.MethodOptions && r.opts.AutoOptions {
+			r.serveOptions(w, req, allowed)
+			return
+		}
+
 		if r.MethodNotAllowedHandler != nil {
 			r.MethodNotAllowedHandler(w, req, nil)
 		} else {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 		}
+		return
+	}
+
+	if r.NotFoundHandler != nil {
+		r.NotFoundHandler(w, req, nil)
+	} else {
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// fixPath returns the canonical path of a route matching path without its trailing slash
+// and/or ignoring case, depending on the options, or "" if there's none.
+// It's only called after an exact match failed, so it doesn't slow down the common case.
+// Paths starting with // or /\ are never returned, browsers treat them as another host, ex: //evil.com.
+func (r *Router) fixPath(method, path string) string {
+	if p := r.findFixedPath(method, path); !strings.HasPrefix(p, "//") && !strings.HasPrefix(p, "/\\") {
+		return p
+	}
+	return ""
+}
+
+func (r *Router) findFixedPath(method, path string) string {
+	t := r.tbl.Load().tree(method)
+	if t == nil {
+		return ""
+	}
+
+	candidates := []string{path}
+	if r.opts.RedirectTrailingSlash && len(path) > 1 && path[len(path)-1] == '/' {
+		p := path[:len(path)-1]
+		if rn, ps := r.matchTree(t, p, false); rn != nil {
+			r.putParams(ps)
+			return p
+		}
+		candidates = append(candidates, p)
+	}
+
+	if r.opts.RedirectCaseInsensitive {
+		m := matcher{r: r}
+		for _, p := range candidates {
+			if fixed, rn := t.root.findFold(p, &m, make([]byte, 0, len(p))); rn != nil && string(fixed) != path {
+				return string(fixed)
+			}
+		}
 	}
+	return ""
 }
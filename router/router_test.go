@@ -1,9 +1,19 @@
 package router
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestRouter(t *testing.T) {
@@ -36,6 +46,1140 @@ func TestRouterStar(t *testing.T) {
 	}
 }
 
+func TestRouterPrecedence(t *testing.T) {
+	tests := []struct {
+		routes []string
+		path   string
+		route  string
+		params Params
+	}{
+		{[]string{"/users/:id", "/users/me"}, "/users/me", "/users/me", nil},
+		{[]string{"/users/me", "/users/:id"}, "/users/me", "/users/me", nil},
+		{[]string{"/users/me", "/users/:id"}, "/users/mee", "/users/:id", Params{{"id", "mee"}}},
+		{[]string{"/a/*rest", "/a/b"}, "/a/b", "/a/b", nil},
+		{[]string{"/a/*rest", "/a/:id"}, "/a/b", "/a/:id", Params{{"id", "b"}}},
+		{[]string{"/a/*rest", "/a/:id"}, "/a/b/c", "/a/*rest", Params{{"rest", "b/c"}}},
+		{[]string{"/a/b/d", "/a/:x/c"}, "/a/b/c", "/a/:x/c", Params{{"x", "b"}}},
+		{[]string{"/a/b/*rest", "/a/:x/c"}, "/a/b/c", "/a/b/*rest", Params{{"rest", "c"}}},
+		{[]string{"/a/:x/c", "/*all"}, "/a/b/d", "/*all", Params{{"all", "a/b/d"}}},
+		{[]string{"/static/*fp"}, "/static", "/static/*fp", Params{{"fp", ""}}},
+		{[]string{"/static/*fp", "/static"}, "/static", "/static", nil},
+		{[]string{"/:a/:b", "/:a/x/:c"}, "/1/x", "/:a/:b", Params{{"a", "1"}, {"b", "x"}}},
+		{[]string{"/:a/:b", "/:a/x/:c"}, "/1/x/2", "/:a/x/:c", Params{{"a", "1"}, {"c", "2"}}},
+		{[]string{"/users/:id"}, "/users/", "", nil},
+		{[]string{"/users/:id"}, "/users", "", nil},
+		{[]string{"/users/:id/posts"}, "/users//posts", "", nil},
+		{[]string{"/:a/:b"}, "//x", "", nil},
+	}
+
+	for _, tc := range tests {
+		r := New(nil)
+		for _, rt := range tc.routes {
+			r.AddRoute("", "GET", rt, nil)
+		}
+		rn, p := r.Match("GET", tc.path)
+		if tc.route == "" {
+			if rn != nil {
+				t.Errorf("%v %s: expected no match, got %s", tc.routes, tc.path, rn.Path())
+			}
+			continue
+		}
+		if rn == nil || rn.Path() != tc.route {
+			t.Errorf("%v %s: expected %s, got %v", tc.routes, tc.path, tc.route, rn)
+			continue
+		}
+		if len(p) != len(tc.params) {
+			t.Errorf("%v %s: expected %v, got %v", tc.routes, tc.path, tc.params, p)
+			continue
+		}
+		for i := range p {
+			if p[i] != tc.params[i] {
+				t.Errorf("%v %s: expected %v, got %v", tc.routes, tc.path, tc.params, p)
+			}
+		}
+	}
+}
+
+func TestRouterCustomMethods(t *testing.T) {
+	r := New(nil)
+	r.AddRoute("", "QUERY", "/search/:index", nil).WithDoc("search", true)
+	r.AddRoute("", "PROPFIND", "/dav/*path", nil)
+	r.AddRoute("", "GET", "/search/:index", nil).WithDoc("search", true)
+
+	if rn, p := r.Match("QUERY", "/search/users"); rn == nil || p.Get("index") != "users" {
+		t.Fatalf("expected a match, got %v %v", rn, p)
+	}
+	if rn, p := r.Match("PROPFIND", "/dav/a/b"); rn == nil || p.Get("path") != "a/b" {
+		t.Fatalf("expected a match, got %v %v", rn, p)
+	}
+	if rn, _ := r.Match("query", "/search/users"); rn != nil {
+		t.Fatal("methods are case-sensitive")
+	}
+	if !r.DisableRoute("PROPFIND", "/dav/*path", true) {
+		t.Fatal("expected DisableRoute to return true")
+	}
+	if routes := r.GetRoutes(); len(routes) != 2 || routes[1][1] != "GET" {
+		t.Fatalf("unexpected routes: %v", routes)
+	}
+
+	j, err := json.Marshal(r.Swagger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(j), `"x-additionalOperations":{"QUERY":{`) || !strings.Contains(string(j), `"get":{`) {
+		t.Fatalf("unexpected swagger: %s", j)
+	}
+	// additionalOperations is OpenAPI 3.2 only
+	if !strings.Contains(string(j), `"openapi":"3.1.0"`) || strings.Contains(string(j), `"additionalOperations"`) {
+		t.Fatalf("unexpected swagger: %s", j)
+	}
+
+	for _, m := range []string{"", "BAD METHOD", "GET/", "(GET)"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected %q to panic", m)
+				}
+			}()
+			r.AddRoute("", m, "/", nil)
+		}()
+	}
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	r := New(nil)
+	fn := func(w http.ResponseWriter, req *http.Request, p Params) {}
+	r.AddRoute("", "POST", "/users", fn)
+	r.AddRoute("", "GET", "/users/:id", fn)
+	r.AddRoute("", "DELETE", "/users/:id", fn)
+	r.AddRoute("", "QUERY", "/users/:id", fn)
+	r.AddRoute("", "PUT", "/users/:id", fn)
+	r.DisableRoute("PUT", "/users/:id", true)
+
+	var allowed []string
+	r.MethodNotAllowedHandler = func(w http.ResponseWriter, req *http.Request, _ Params) {
+		allowed = AllowedFromRequest(req)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+
+	tests := []struct {
+		method, path string
+		code         int
+		allow        string
+	}{
+		{"GET", "/users", http.StatusMethodNotAllowed, "POST"},
+		{"GET", "/users%3Fx", http.StatusMethodNotAllowed, "POST"}, // routed as /users
+		{"PATCH", "/users/1", http.StatusMethodNotAllowed, "GET, HEAD, DELETE, QUERY"},
+		{"PUT", "/users/1", http.StatusMethodNotAllowed, "GET, HEAD, DELETE, QUERY"},
+		{"GET", "/nope", http.StatusNotFound, ""},
+		{"POST", "/nope", http.StatusNotFound, ""},
+	}
+
+	for _, tc := range tests {
+		allowed = nil
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.path, tc.code, w.Code)
+		}
+		if got := w.Header().Get("Allow"); got != tc.allow {
+			t.Errorf("%s %s: expected Allow: %q, got %q", tc.method, tc.path, tc.allow, got)
+		}
+		if got := strings.Join(allowed, ", "); got != tc.allow {
+			t.Errorf("%s %s: expected allowed %q, got %q", tc.method, tc.path, tc.allow, got)
+		}
+	}
+}
+
+func TestRouterAutoOptions(t *testing.T) {
+	r := New(&Options{AutoOptions: true, CORS: &CORS{Origins: []string{"*.example.com"}}})
+	fn := func(w http.ResponseWriter, req *http.Request, p Params) {}
+	r.AddRoute("", "GET", "/users/:id", fn)
+	r.AddRoute("", "DELETE", "/users/:id", fn)
+	r.AddRoute("", "OPTIONS", "/custom", func(w http.ResponseWriter, req *http.Request, p Params) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tests := []struct {
+		path, origin string
+		code         int
+		allow, cors  string
+	}{
+		{"/users/1", "", http.StatusNoContent, "GET, HEAD, DELETE, OPTIONS", ""},
+		{"/users/1", "https://app.example.com", http.StatusNoContent, "GET, HEAD, DELETE, OPTIONS", "GET, HEAD, DELETE, OPTIONS"},
+		{"/users/1", "https://evil.com", http.StatusNoContent, "GET, HEAD, DELETE, OPTIONS", ""},
+		{"/custom", "https://app.example.com", http.StatusTeapot, "", ""},
+		{"/nope", "https://app.example.com", http.StatusNotFound, "", ""},
+	}
+
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodOptions, tc.path, nil)
+		if tc.origin != "" {
+			req.Header.Set("Origin", tc.origin)
+			req.Header.Set("Access-Control-Request-Method", "DELETE")
+		}
+		r.ServeHTTP(w, req)
+		h := w.Header()
+		if w.Code != tc.code || h.Get("Allow") != tc.allow || h.Get("Access-Control-Allow-Methods") != tc.cors {
+			t.Errorf("%s (%s): unexpected response %d %v", tc.path, tc.origin, w.Code, h)
+		}
+		if tc.cors != "" && h.Get("Access-Control-Allow-Origin") != tc.origin {
+			t.Errorf("%s (%s): unexpected origin %v", tc.path, tc.origin, h)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/1", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, HEAD, DELETE, OPTIONS" {
+		t.Errorf("unexpected response %d %v", w.Code, w.Header())
+	}
+}
+
+func TestRouterConstraints(t *testing.T) {
+	r := New(nil)
+	r.AddRoute("", "GET", "/users/:id<int>", nil).WithDoc("get user", true)
+	r.AddRoute("", "GET", "/users/:name<alpha>", nil)
+	r.AddRoute("", "GET", "/users/:other", nil)
+	r.AddRoute("", "GET", "/objects/:id<uuid>/meta", nil)
+	r.AddRoute("", "GET", "/posts/:slug<[a-z0-9-]{3,}>", nil).WithDoc("get post", true)
+	r.AddRoute("", "GET", "/n/:n<uint>", nil)
+
+	tests := []struct {
+		path, route string
+	}{
+		{"/users/42", "/users/:id<int>"},
+		{"/users/-42", "/users/:id<int>"},
+		{"/users/bob", "/users/:name<alpha>"},
+		{"/users/bob42", "/users/:other"},
+		{"/objects/0f8fad5b-d9cb-469f-a165-70867728950e/meta", "/objects/:id<uuid>/meta"},
+		{"/objects/0f8fad5b-d9cb-469f-a165-70867728950/meta", ""},
+		{"/posts/hello-world", "/posts/:slug<[a-z0-9-]{3,}>"},
+		{"/posts/Hello", ""},
+		{"/posts/hi", ""},
+		{"/n/1", "/n/:n<uint>"},
+		{"/n/-1", ""},
+	}
+
+	for _, tc := range tests {
+		rn, _ := r.Match("GET", tc.path)
+		if tc.route == "" {
+			if rn != nil {
+				t.Errorf("%s: expected no match, got %s", tc.path, rn.Path())
+			}
+			continue
+		}
+		if rn == nil || rn.Path() != tc.route {
+			t.Errorf("%s: expected %s, got %v", tc.path, tc.route, rn)
+		}
+	}
+
+	_, p := r.Match("GET", "/users/42")
+	if id, err := p.GetInt("id"); err != nil || id != 42 {
+		t.Fatalf("unexpected id: %v %v", id, err)
+	}
+
+	sp := r.Swagger().Paths
+	if sc := sp["/users/:id<int>"]["get"].Parameters[0].Schema; sc.Type != "integer" || sc.Format != "int64" {
+		t.Fatalf("unexpected schema: %+v", sc)
+	}
+	if sc := sp["/posts/:slug<[a-z0-9-]{3,}>"]["get"].Parameters[0].Schema; sc.Type != "string" || sc.Pattern != "^(?:[a-z0-9-]{3,})$" {
+		t.Fatalf("unexpected schema: %+v", sc)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected an invalid regexp to panic")
+		}
+	}()
+	r.AddRoute("", "GET", "/bad/:id<[a-z>", nil)
+}
+
+func TestRouterMultiParamsAndOptional(t *testing.T) {
+	r := New(nil)
+	r.AddRoute("", "GET", "/files/:name.:ext", nil)
+	r.AddRoute("", "GET", "/files/readme.md", nil)
+	r.AddRoute("", "GET", "/files/:name", nil)
+	r.AddRoute("", "GET", "/posts/:year<int>/:month<int>?", nil)
+	r.AddRoute("", "GET", "/posts/latest", nil)
+	r.AddRoute("", "GET", "/dl/:name.:ext?", nil)
+	r.AddRoute("", "GET", "/range/:from~:to/:start-date", nil)
+
+	tests := []struct {
+		path, route string
+		params      Params
+	}{
+		{"/files/report.json", "/files/:name.:ext", Params{{"name", "report"}, {"ext", "json"}}},
+		{"/files/report.tar.gz", "/files/:name.:ext", Params{{"name", "report.tar"}, {"ext", "gz"}}},
+		{"/files/readme.md", "/files/readme.md", nil},
+		{"/files/readme", "/files/:name", Params{{"name", "readme"}}},
+		{"/files/.gz", "/files/:name", Params{{"name", ".gz"}}},
+		{"/posts/2024/05", "/posts/:year<int>/:month<int>?", Params{{"year", "2024"}, {"month", "05"}}},
+		{"/posts/2024", "/posts/:year<int>/:month<int>?", Params{{"year", "2024"}}},
+		{"/posts/latest", "/posts/latest", nil},
+		{"/posts/2024/may", "", nil},
+		{"/dl/a.zip", "/dl/:name.:ext?", Params{{"name", "a"}, {"ext", "zip"}}},
+		{"/dl/a", "/dl/:name.:ext?", Params{{"name", "a"}}},
+		{"/range/1~5/today", "/range/:from~:to/:start-date", Params{{"from", "1"}, {"to", "5"}, {"start-date", "today"}}},
+	}
+
+	for _, tc := range tests {
+		rn, p := r.Match("GET", tc.path)
+		if tc.route == "" {
+			if rn != nil {
+				t.Errorf("%s: expected no match, got %s", tc.path, rn.Path())
+			}
+			continue
+		}
+		if rn == nil || rn.Path() != tc.route {
+			t.Errorf("%s: expected %s, got %v", tc.path, tc.route, rn)
+			continue
+		}
+		if len(p) != len(tc.params) {
+			t.Errorf("%s: expected %v, got %v", tc.path, tc.params, p)
+			continue
+		}
+		for i := range p {
+			if p[i] != tc.params[i] {
+				t.Errorf("%s: expected %v, got %v", tc.path, tc.params, p)
+			}
+		}
+	}
+
+	for _, bad := range []string{"/a/:x?/b", "/a/:x:y", "/a/:x?/:y", "/a/:x*y"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected %q to panic", bad)
+				}
+			}()
+			r.AddRoute("", "GET", bad, nil)
+		}()
+	}
+}
+
+func TestRouterRemoveRoute(t *testing.T) {
+	r := New(nil)
+	r.AddRoute("", "GET", "/users/:id", nil)
+	r.AddRoute("", "GET", "/users/me", nil)
+	r.AddRoute("", "GET", "/posts/:year/:month?", nil)
+	r.AddRoute("", "GET", "/static/*fp", nil)
+
+	if rn := r.RemoveRoute("GET", "/users/me"); rn == nil || rn.Path() != "/users/me" {
+		t.Fatalf("unexpected removed route: %v", rn)
+	}
+	if rn, p := r.Match("GET", "/users/me"); rn == nil || rn.Path() != "/users/:id" || p.Get("id") != "me" {
+		t.Fatalf("expected /users/:id, got %v %v", rn, p)
+	}
+
+	// only the exact pattern removes a route, not a path it matches
+	if rn := r.RemoveRoute("GET", "/users/42"); rn != nil {
+		t.Fatalf("unexpected removed route: %v", rn.Path())
+	}
+	if rn, _ := r.Match("GET", "/users/42"); rn == nil {
+		t.Fatal("expected /users/:id to still match")
+	}
+	if rn := r.RemoveRoute("GET", "/users/:id"); rn == nil || rn.Path() != "/users/:id" {
+		t.Fatalf("unexpected removed route: %v", rn)
+	}
+	if rn, _ := r.Match("GET", "/users/me"); rn != nil {
+		t.Fatalf("expected no match, got %v", rn.Path())
+	}
+
+	if rn := r.RemoveRoute("GET", "/posts/:year/:month?"); rn == nil {
+		t.Fatal("expected a removed route")
+	}
+	for _, path := range []string{"/posts/2024", "/posts/2024/05"} {
+		if rn, _ := r.Match("GET", path); rn != nil {
+			t.Fatalf("%s: expected no match, got %v", path, rn.Path())
+		}
+	}
+
+	if rn := r.RemoveRoute("GET", "/static/*fp"); rn == nil {
+		t.Fatal("expected a removed route")
+	}
+	if rn, _ := r.Match("GET", "/static"); rn != nil {
+		t.Fatalf("expected no match, got %v", rn.Path())
+	}
+
+	if rn := r.RemoveRoute("GET", "/nope"); rn != nil {
+		t.Fatalf("expected nil, got %v", rn.Path())
+	}
+	if routes := r.GetRoutes(); len(routes) != 0 {
+		t.Fatalf("expected no routes, got %v", routes)
+	}
+
+	r.AddRoute("", "GET", "/users/me", nil)
+	if rn, _ := r.Match("GET", "/users/me"); rn == nil || rn.Path() != "/users/me" {
+		t.Fatalf("expected /users/me, got %v", rn)
+	}
+}
+
+func TestRouterDisableAndReplace(t *testing.T) {
+	var (
+		r    = New(nil)
+		body = func(s string) Handler {
+			return func(w http.ResponseWriter, _ *http.Request, _ Params) { w.Write([]byte(s)) }
+		}
+		get = func(path string) string {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			return strconv.Itoa(w.Code) + " " + w.Body.String()
+		}
+	)
+	me := r.AddRoute("", "GET", "/users/me", body("me"))
+	r.AddRoute("", "GET", "/users/:id", body("id")).WithDoc("get user", true)
+	r.AddRoute("", "GET", "/files/*fp", body("files"))
+
+	// only the exact pattern disables a route
+	if r.DisableRoute("GET", "/users/42", true) {
+		t.Fatal("expected DisableRoute to need the exact pattern")
+	}
+	if got := get("/users/42"); got != "200 id" {
+		t.Fatalf("unexpected response: %s", got)
+	}
+
+	// a disabled route doesn't shadow the next best match
+	r.DisableRoute("GET", "/users/me", true)
+	if got := get("/users/me"); got != "200 id" {
+		t.Fatalf("unexpected response: %s", got)
+	}
+	if routes := r.GetRoutes(); len(routes) != 2 {
+		t.Fatalf("unexpected routes: %v", routes)
+	}
+	if r.Route("GET", "/users/me") != me {
+		t.Fatal("expected Route to return the disabled route")
+	}
+	r.DisableRoute("GET", "/users/me", false)
+	if got := get("/users/me"); got != "200 me" {
+		t.Fatalf("unexpected response: %s", got)
+	}
+
+	r.DisableRoute("GET", "/files/*fp", true)
+	if got := get("/files"); got != "404 " {
+		t.Fatalf("unexpected response: %s", got)
+	}
+
+	me.SetHandler(body("replaced"))
+	if got := get("/users/me"); got != "200 replaced" {
+		t.Fatalf("unexpected response: %s", got)
+	}
+
+	if _, ok := r.Swagger().Paths["/users/:id"]; !ok {
+		t.Fatal("expected /users/:id in swagger")
+	}
+	r.RemoveRoute("GET", "/users/:id")
+	if _, ok := r.Swagger().Paths["/users/:id"]; ok {
+		t.Fatal("expected /users/:id to be removed from swagger")
+	}
+}
+
+func TestRouterURL(t *testing.T) {
+	r := New(nil)
+	r.AddRoute("", "GET", "/users/:id<int>", nil).Name("user.show")
+	r.AddRoute("", "GET", "/files/:name.:ext", nil).Name("file")
+	r.AddRoute("", "GET", "/posts/:year/:month?", nil).Name("posts")
+	r.AddRoute("", "GET", "/static/*fp", nil).Name("static")
+	r.AddRoute("", "GET", "/", nil).Name("home")
+
+	tests := []struct {
+		name   string
+		params []string
+		url    string
+		err    error
+	}{
+		{"user.show", []string{"id", "42"}, "/users/42", nil},
+		{"user.show", []string{"id", "x"}, "", ErrInvalidParam},
+		{"user.show", nil, "", ErrMissingParam},
+		{"user.show", []string{"id"}, "", ErrInvalidParam},
+		{"file", []string{"name", "a/b", "ext", "txt"}, "", ErrInvalidParam},
+		{"file", []string{"ext", "tar.gz", "name", "a b"}, "/files/a%20b.tar.gz", nil},
+		{"posts", []string{"year", "2024", "month", "05"}, "/posts/2024/05", nil},
+		{"posts", []string{"year", "2024"}, "/posts/2024", nil},
+		{"posts", []string{"month", "05"}, "", ErrMissingParam},
+		{"static", []string{"fp", "a dir/b?.txt"}, "/static/a%20dir/b%3F.txt", nil},
+		{"static", []string{"fp", ""}, "/static/", nil},
+		{"static", nil, "/static/", nil},
+		{"home", nil, "/", nil},
+		{"nope", nil, "", ErrUnknownRoute},
+	}
+
+	for _, tc := range tests {
+		u, err := r.URL(tc.name, tc.params...)
+		if !errors.Is(err, tc.err) || u != tc.url {
+			t.Errorf("%s %v: expected (%q, %v), got (%q, %v)", tc.name, tc.params, tc.url, tc.err, u, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if rn, _ := r.Match("GET", u); rn != r.Named(tc.name) {
+			t.Errorf("%s: %s doesn't match its own route", tc.name, u)
+		}
+	}
+
+	func() {
+		defer func() {
+			if err, _ := recover().(error); !errors.Is(err, ErrDuplicateName) {
+				t.Errorf("expected a duplicate name to panic with ErrDuplicateName, got %v", err)
+			}
+		}()
+		r.AddRoute("", "GET", "/other", nil).Name("home")
+	}()
+	if err := r.AddRoute("", "GET", "/other2", nil).NameE("home"); !errors.Is(err, ErrDuplicateName) {
+		t.Fatalf("expected ErrDuplicateName, got %v", err)
+	}
+
+	lenient := New(&Options{NoPanicOnInvalidAddRoute: true})
+	first := lenient.AddRoute("", "GET", "/a", nil).Name("a")
+	if lenient.AddRoute("", "GET", "/b", nil).Name("a"); lenient.Named("a") != first {
+		t.Fatal("expected the existing name to be kept")
+	}
+
+	home := r.RemoveRoute("GET", "/")
+	if _, err := r.URL("home"); !errors.Is(err, ErrUnknownRoute) {
+		t.Fatalf("expected ErrUnknownRoute, got %v", err)
+	}
+	if home.Name("home.removed"); r.Named("home.removed") != nil {
+		t.Fatal("a removed route shouldn't be named")
+	}
+}
+
+func TestRouterRouteMiddlewareAndMeta(t *testing.T) {
+	type scopes []string
+	type timeout int
+
+	var (
+		r     = New(nil)
+		trace []string
+		mw    = func(name string) Middleware {
+			return func(next Handler) Handler {
+				return func(w http.ResponseWriter, req *http.Request, p Params) {
+					trace = append(trace, name)
+					next(w, req, p)
+				}
+			}
+		}
+		h = func(name string) Handler {
+			return func(w http.ResponseWriter, req *http.Request, p Params) {
+				s, _ := Meta[scopes](RouteFromRequest(req))
+				trace = append(trace, name+":"+strings.Join(s, ","))
+			}
+		}
+	)
+
+	rn := r.AddRoute("", "GET", "/admin", h("h")).Use(mw("a"), mw("b"))
+	SetMeta(rn, scopes{"admin", "write"})
+	SetMeta(rn, timeout(5))
+	r.AddRoute("", "GET", "/public", h("public"))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/admin", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/public", nil))
+	if got := strings.Join(trace, " "); got != "a b h:admin,write public:" {
+		t.Fatalf("unexpected trace: %s", got)
+	}
+
+	trace = nil
+	rn.SetHandler(h("replaced"))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/admin", nil))
+	if got := strings.Join(trace, " "); got != "a b replaced:admin,write" {
+		t.Fatalf("unexpected trace: %s", got)
+	}
+
+	if v, ok := Meta[timeout](rn); !ok || v != 5 {
+		t.Fatalf("unexpected timeout: %v %v", v, ok)
+	}
+	if _, ok := Meta[string](rn); ok {
+		t.Fatal("unexpected string meta")
+	}
+	if _, ok := Meta[scopes](nil); ok {
+		t.Fatal("unexpected meta on a nil route")
+	}
+}
+
+func TestRouterRedirects(t *testing.T) {
+	r := New(&Options{RedirectTrailingSlash: true, RedirectCaseInsensitive: true})
+	for _, p := range []string{"/users", "/users/:id<int>/Posts", "/files/*fp", "/Docs/:name.:ext"} {
+		r.AddRoute("", "GET", p, func(w http.ResponseWriter, req *http.Request, p Params) {})
+		r.AddRoute("", "POST", p, func(w http.ResponseWriter, req *http.Request, p Params) {})
+	}
+
+	tests := []struct {
+		method, path string
+		code         int
+		loc          string
+	}{
+		{"GET", "/users", 200, ""},
+		{"GET", "/users/", 301, "/users"},
+		{"GET", "/users/?q=1", 301, "/users?q=1"},
+		{"POST", "/users/", 308, "/users"},
+		{"HEAD", "/USERS", 301, "/users"},
+		{"GET", "/Users/", 301, "/users"},
+		{"GET", "/USERS/42/posts", 301, "/users/42/Posts"},
+		{"GET", "/users/x/posts", 404, ""},
+		{"GET", "/FILES/Some/Path", 301, "/files/Some/Path"},
+		{"GET", "/docs/README.MD", 301, "/Docs/README.MD"},
+		{"GET", "/nope/", 404, ""},
+	}
+
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.code || w.Header().Get("Location") != tc.loc {
+			t.Errorf("%s %s: expected %d %q, got %d %q", tc.method, tc.path, tc.code, tc.loc, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	// paths starting with // or /\ would redirect to another host
+	r = New(&Options{RedirectTrailingSlash: true, RedirectCaseInsensitive: true, NoAutoCleanURL: true})
+	r.AddRoute("", "GET", "/:a/:b", func(w http.ResponseWriter, req *http.Request, p Params) {})
+	for _, path := range []string{"//evil.com/", "/\\evil.com/", "//EVIL.com/x/"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.URL.Path = path
+		r.ServeHTTP(w, req)
+		if loc := w.Header().Get("Location"); loc != "" {
+			t.Errorf("%s: unexpected redirect to %s", path, loc)
+		}
+	}
+
+	r = New(nil)
+	r.AddRoute("", "GET", "/users", func(w http.ResponseWriter, req *http.Request, p Params) {})
+	for _, path := range []string{"/users/", "/Users"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != 404 {
+			t.Errorf("%s: expected 404 without the options, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestRouterMount(t *testing.T) {
+	r := New(nil)
+	body := func(s string) Handler {
+		return func(w http.ResponseWriter, req *http.Request, p Params) {
+			w.Write([]byte(s + ":" + p.Get("mountPath")))
+		}
+	}
+	r.Mount("admin", "/admin/", body("mount"))
+	r.AddRoute("", "GET", "/admin/health", body("health"))
+	r.AddRoute("", "POST", "/users", body("users"))
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/admin", 200, "mount:"},
+		{"PROPFIND", "/admin/a/b", 200, "mount:a/b"},
+		{"DELETE", "/admin/health", 200, "mount:health"},
+		{"GET", "/admin/health", 200, "health:"},
+		{"GET", "/users", 405, ""},
+		{"GET", "/other", 404, ""},
+	}
+
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.code || w.Body.String() != tc.body {
+			t.Errorf("%s %s: expected %d %q, got %d %q", tc.method, tc.path, tc.code, tc.body, w.Code, w.Body.String())
+		}
+	}
+
+	if routes := r.GetRoutes(); len(routes) != 3 || routes[0] != [3]string{"admin", MethodAny, "/admin/*mountPath"} {
+		t.Fatalf("unexpected routes: %v", routes)
+	}
+}
+
+func testHandler(w http.ResponseWriter, req *http.Request, p Params) {}
+
+func TestRouterRoutesInfo(t *testing.T) {
+	r := New(nil)
+	mw := func(next Handler) Handler { return next }
+	r.AddRoute("users", "DELETE", "/users/:id", testHandler)
+	r.AddRoute("users", "GET", "/users/:id", testHandler).Use(mw).Name("user.show").WithDoc("get user", false)
+	r.AddRoute("users", "GET", "/users/me", nil)
+	SetMeta(r.AddRoute("", "GET", "/", testHandler), ChainInfo{Handlers: []string{"a", "b"}, Middleware: []string{"m"}})
+	r.DisableRoute("GET", "/users/me", true)
+
+	routes := r.Routes()
+	exp := []RouteInfo{
+		{Method: "GET", Pattern: "/", Handlers: []string{"a", "b"}, Middleware: []string{"m"}},
+		{Method: "GET", Pattern: "/users/:id", Group: "users", Name: "user.show", Handlers: []string{"router.testHandler"}, Middleware: []string{"router.TestRouterRoutesInfo.func1"}, HasDoc: true},
+		{Method: "DELETE", Pattern: "/users/:id", Group: "users", Handlers: []string{"router.testHandler"}},
+		{Method: "GET", Pattern: "/users/me", Group: "users", Handlers: []string{"<nil>"}, Disabled: true},
+	}
+	if got, want := fmt.Sprint(routes), fmt.Sprint(exp); got != want {
+		t.Fatalf("unexpected routes:\n%s\n%s", got, want)
+	}
+
+	var buf strings.Builder
+	if err := WriteRoutesTree(&buf, routes); err != nil {
+		t.Fatal(err)
+	}
+	tree := "" +
+		"/            GET            a, b                mw: m\n" +
+		"└── users\n" +
+		"    ├── :id  GET     users  router.testHandler  mw: router.TestRouterRoutesInfo.func1  name: user.show  doc\n" +
+		"    │        DELETE  users  router.testHandler\n" +
+		"    └── me   GET     users  <nil>               disabled\n"
+	if buf.String() != tree {
+		t.Fatalf("unexpected tree:\n%s", buf.String())
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/debug/routes?format=json", nil)
+	r.DebugHandler()(w, req, nil)
+	var decoded []RouteInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &decoded); err != nil || len(decoded) != 4 || !decoded[3].Disabled {
+		t.Fatalf("unexpected json: %v %s", err, w.Body.String())
+	}
+}
+
+func TestRouterConflicts(t *testing.T) {
+	r := New(nil)
+	id := r.AddRoute("users", "GET", "/users/:id", testHandler)
+	r.AddRoute("", "GET", "/users/:id<int>", testHandler)
+	posts := r.AddRoute("", "GET", "/posts/:year/:month?", testHandler)
+	r.AddRoute("", "POST", "/users/:name", testHandler)
+	r.AddRoute("", MethodAny, "/users/:name", testHandler)
+
+	tests := []struct {
+		method, pattern string
+		existing        *Route
+	}{
+		{"GET", "/users/:name", id},
+		{"GET", "/users/:id/", id},
+		{"GET", "/posts/:y", posts},
+		{"GET", "/posts/:y/:m", posts},
+	}
+
+	for _, tc := range tests {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				var ce *ConflictError
+				if !errors.As(err, &ce) || !errors.Is(err, ErrRouteConflict) || ce.Existing != tc.existing {
+					t.Errorf("%s %s: expected a conflict with %s, got %v", tc.method, tc.pattern, tc.existing.fp, err)
+				}
+			}()
+			r.AddRoute("", tc.method, tc.pattern, testHandler)
+		}()
+	}
+
+	// the existing route keeps serving, and a conflicting optional variant doesn't leave the others behind
+	func() {
+		defer func() { recover() }()
+		r.AddRoute("", "GET", "/users/:id/files/:name?", testHandler)
+		r.AddRoute("", "GET", "/users/:id/files", testHandler)
+	}()
+	func() {
+		defer func() { recover() }()
+		r.AddRoute("", "PUT", "/a", testHandler)
+		r.AddRoute("", "PUT", "/a/:x?", testHandler)
+	}()
+	if rn, _ := r.Match("PUT", "/a/b"); rn != nil {
+		t.Fatalf("unexpected match: %s", rn.fp)
+	}
+	if rn, _ := r.Match("GET", "/users/1/files/x"); rn == nil {
+		t.Fatal("expected a match")
+	}
+
+	// different constraints don't conflict even if they overlap, the first one registered wins
+	ints := r.AddRoute("", "GET", "/n/:id<int>", testHandler)
+	uints := r.AddRoute("", "GET", "/n/:id<uint>", testHandler)
+	if rn, _ := r.Match("GET", "/n/1"); rn != ints {
+		t.Fatalf("expected %s, got %v", ints.fp, rn)
+	}
+	if rn, _ := r.Match("GET", "/n/-1"); rn != ints {
+		t.Fatalf("expected %s, got %v", ints.fp, rn)
+	}
+	r.RemoveRoute("GET", "/n/:id<int>")
+	if rn, _ := r.Match("GET", "/n/1"); rn != uints {
+		t.Fatalf("expected %s, got %v", uints.fp, rn)
+	}
+
+	r = New(&Options{NoPanicOnInvalidAddRoute: true})
+	r.AddRoute("", "GET", "/users/:id", testHandler)
+	if rn := r.AddRoute("", "GET", "/users/:name", testHandler); rn != nil || len(r.GetRoutes()) != 1 {
+		t.Fatalf("expected nil, got %v %v", rn, r.GetRoutes())
+	}
+}
+
+func TestRouterAddRouteE(t *testing.T) {
+	r := New(nil)
+	if _, err := r.AddRouteE("", "GET", "/users/:id", testHandler); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method, pattern string
+		err             error
+		param           string
+	}{
+		{"BAD METHOD", "/", ErrInvalidMethod, ""},
+		{"GET", "/a/*x/b", ErrStarNotLast, "x"},
+		{"GET", "/a/*x/*y", ErrTooManyStars, ""},
+		{"GET", "/a/:/b", ErrEmptyParamName, ""},
+		{"GET", "/a/*", ErrEmptyParamName, ""},
+		{"GET", "/a/:id/b/:id", ErrDuplicateParamName, "id"},
+		{"GET", "/a/:x?/b", ErrInvalidPattern, ""},
+		{"GET", "/a/:id<[a-z>", ErrInvalidPattern, ""},
+		{"GET", "/users/:name", ErrRouteConflict, ""},
+	}
+
+	for _, tc := range tests {
+		rn, err := r.AddRouteE("", tc.method, tc.pattern, testHandler)
+		if rn != nil || !errors.Is(err, tc.err) {
+			t.Errorf("%s %s: expected %v, got %v", tc.method, tc.pattern, tc.err, err)
+			continue
+		}
+		var re *RouteError
+		if errors.As(err, &re) && (re.Param != tc.param || re.Pattern != tc.pattern) {
+			t.Errorf("%s %s: unexpected error: %+v", tc.method, tc.pattern, re)
+		}
+	}
+
+	if routes := r.GetRoutes(); len(routes) != 1 {
+		t.Fatalf("unexpected routes: %v", routes)
+	}
+
+	r = New(&Options{NoPanicOnInvalidAddRoute: true})
+	if rn := r.AddRoute("", "GET", "/a/*x/b", testHandler); rn != nil {
+		t.Fatal("expected nil")
+	}
+}
+
+type schemaBase struct {
+	ID      int64     `json:"id" validate:"required"`
+	Created time.Time `json:"created"`
+}
+
+type schemaUser struct {
+	schemaBase
+	Name    string              `json:"name" validate:"required"`
+	Email   string              `json:"email,omitempty"`
+	Age     int                 `json:"age,string"`
+	Tags    []string            `json:"tags"`
+	Attrs   map[string]float64  `json:"attrs,omitempty"`
+	Avatar  []byte              `json:"avatar,omitempty"`
+	Friends []*schemaUser       `json:"friends,omitempty"`
+	Boss    *schemaUser         `json:"boss"`
+	Extra   any                 `json:"extra"`
+	Secret  string              `json:"-"`
+	Page    schemaPage[int]     `json:"page" validate:"required"`
+	Anon    struct{ X float32 } `json:"anon"`
+	hidden  bool
+}
+
+type schemaPage[T any] struct {
+	Items []T `json:"items" validate:"required"`
+}
+
+func TestRouterOpenAPI(t *testing.T) {
+	r := New(nil)
+	rn := r.AddRoute("users", "PUT", "/users/:id<int>/:tab?", testHandler)
+	rn.Doc().
+		WithBodySchema("application/json", r.SchemaOf(reflect.TypeFor[schemaUser]())).
+		WithResponseSchema("200", "OK", "application/json", r.SchemaOf(reflect.TypeFor[[]schemaUser]()))
+	rn.WithDoc("update user", true).WithOperationID("updateUser")
+
+	j, err := json.Marshal(r.Swagger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		OpenAPI    string
+		Info       map[string]string
+		Paths      map[string]map[string]json.RawMessage
+		Components struct {
+			Schemas map[string]json.RawMessage
+		}
+	}
+	if err := json.Unmarshal(j, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.OpenAPI != "3.1.0" || doc.Info["title"] == "" || len(doc.Paths) != 2 {
+		t.Fatalf("unexpected doc: %s", j)
+	}
+	if op := string(doc.Paths["/users/{id}"]["put"]); !strings.Contains(op, `"operationId":"updateUser"`) ||
+		strings.Contains(op, `"name":"tab"`) || !strings.Contains(op, `"$ref":"#/components/schemas/schemaUser"`) {
+		t.Fatalf("unexpected op: %s", op)
+	}
+	if op := string(doc.Paths["/users/{id}/{tab}"]["put"]); !strings.Contains(op, `is optional","schema":{"type":"string"},"required":true}]`) ||
+		!strings.Contains(op, `"200":{"description":"OK","content":{"application/json":{"schema":{"type":"array","items":{"$ref":"#/components/schemas/schemaUser"}}}}}`) {
+		t.Fatalf("unexpected op: %s", op)
+	}
+
+	exp := map[string]string{
+		"schemaUser": `{"type":"object","required":["id","name","page"],"properties":{` +
+			`"age":{"type":"string"},"anon":{"type":"object","properties":{"X":{"type":"number","format":"float"}}},` +
+			`"attrs":{"type":"object","additionalProperties":{"type":"number","format":"double"}},` +
+			`"avatar":{"type":"string","contentEncoding":"base64"},"boss":{"$ref":"#/components/schemas/schemaUser"},` +
+			`"created":{"type":"string","format":"date-time"},"email":{"type":"string"},"extra":{},` +
+			`"friends":{"type":"array","items":{"$ref":"#/components/schemas/schemaUser"}},"id":{"type":"integer","format":"int64"},` +
+			`"name":{"type":"string"},"page":{"$ref":"#/components/schemas/schemaPage_int"},"tags":{"type":"array","items":{"type":"string"}}}}`,
+		"schemaPage_int": `{"type":"object","required":["items"],"properties":{"items":{"type":"array","items":{"type":"integer","format":"int64"}}}}`,
+	}
+	if len(doc.Components.Schemas) != len(exp) {
+		t.Fatalf("unexpected schemas: %s", j)
+	}
+	for name, s := range exp {
+		if got := string(doc.Components.Schemas[name]); got != s {
+			t.Errorf("%s:\nexpected %s\ngot      %s", name, s, got)
+		}
+	}
+}
+
+func TestRouterOpenAPICollision(t *testing.T) {
+	r := New(nil)
+	r.AddRoute("", "GET", "/n/:id", testHandler).WithDoc("any id", true)
+	r.AddRoute("", "DELETE", "/n/:id<int>", testHandler).WithDoc("int id", true)
+	j, err := json.Marshal(r.Swagger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(j, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if ops := doc.Paths["/n/{id}"]; len(ops) != 2 || ops["get"] == nil || ops["delete"] == nil {
+		t.Fatalf("expected get and delete to be merged: %s", j)
+	}
+
+	r.AddRoute("", "GET", "/n/:id<int>", testHandler).WithDoc("int id", true)
+	if _, err := json.Marshal(r.Swagger()); err == nil ||
+		!strings.Contains(err.Error(), "GET /n/:id and GET /n/:id<int> are both documented as GET /n/{id}") {
+		t.Fatalf("expected a collision error, got %v", err)
+	}
+}
+
+func TestRouterValidate(t *testing.T) {
+	r := New(nil)
+	one, ten, two := 1.0, 10.0, 2
+	rn := r.AddRoute("", "POST", "/users/:id<int>/:tab?", testHandler)
+	rn.Doc().
+		WithParam("limit", "", "query", "integer", true, &SwaggerDefinition{Minimum: &one, Maximum: &ten}).
+		WithParam("sort", "", "query", "string", false, &SwaggerDefinition{Enum: []any{"asc", "desc"}}).
+		WithParam("X-Req-Id", "", "header", "string", false, &SwaggerDefinition{Pattern: "^[a-f0-9]+$"}).
+		WithBodySchema("application/json", r.SchemaOf(reflect.TypeFor[schemaUser]()))
+	r.Swagger().Components.Schemas["schemaUser"].Properties.(map[string]*SwaggerDefinition)["name"].MinLength = &two
+
+	validate := func(query, body string) []ValidationError {
+		req := httptest.NewRequest("POST", "/users/42?"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		req.Header.Set("X-Req-Id", "abc")
+		rn, p := r.Match("POST", "/users/42")
+		errs := rn.Validate(req, p)
+		if b, _ := io.ReadAll(req.Body); string(b) != body {
+			t.Fatalf("body wasn't restored: %q", b)
+		}
+		return errs
+	}
+
+	valid := `{"id": 1, "created": "2024-01-02T03:04:05Z", "name": "bob", "age": "42", "tags": [], "page": {"items": [1, 2]}, "anon": {"X": 1.5},
+		"friends": [{"id": 2, "created": "2024-01-02T03:04:05Z", "name": "al", "age": "1", "tags": null, "page": {"items": []}, "anon": {"X": 0}}]}`
+	if errs := validate("limit=5&sort=asc", valid); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if errs := validate("limit=5", ""); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	errs := validate("limit=50&sort=up", `{"id": 1.5, "created": "yesterday", "name": "b", "age": "42", "tags": [1], "page": {}, "anon": {"X": 1},
+		"friends": [{"name": true}], "attrs": {"a": "x"}}`)
+	exp := []ValidationError{
+		{"query", "limit", "must be <= 10", 0},
+		{"query", "sort", "must be one of [asc desc]", 0},
+		{"body", "attrs.a", "must be a number", 0},
+		{"body", "created", "must be an RFC 3339 date-time", 0},
+		{"body", "friends[0].id", "is required", 0},
+		{"body", "friends[0].page", "is required", 0},
+		{"body", "friends[0].name", "must be a string", 0},
+		{"body", "id", "must be an integer", 0},
+		{"body", "name", "must be at least 2 characters", 0},
+		{"body", "page.items", "is required", 0},
+		{"body", "tags[0]", "must be a string", 0},
+	}
+	sortErrs := func(errs []ValidationError) {
+		sort.SliceStable(errs, func(i, j int) bool {
+			return errs[i].In > errs[j].In || errs[i].In == errs[j].In && errs[i].Field < errs[j].Field
+		})
+	}
+	sortErrs(errs)
+	sortErrs(exp)
+	if !reflect.DeepEqual(errs, exp) {
+		t.Fatalf("expected\n%v\ngot\n%v", exp, errs)
+	}
+
+	if errs := validate("", "[1]"); len(errs) != 2 || errs[0].Error() != "query limit is required" || errs[1].Error() != "body must be an object" {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	r.opts.MaxValidateBodySize = 16
+	big := `{"name": "0123456789abcdef"}`
+	if errs := validate("limit=5", big); len(errs) != 1 || errs[0].Status != http.StatusRequestEntityTooLarge || errs[0].Error() != "body is larger than 16 bytes" {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
+
+func TestRouterValidateTypes(t *testing.T) {
+	r := New(nil)
+	rn := r.AddRoute("", "POST", "/x", testHandler)
+	rn.Doc().
+		WithParam("n", "", "query", "integer", false, nil).
+		WithBodySchema("application/json", &SwaggerDefinition{Type: "object", Properties: map[string]*SwaggerDefinition{
+			"n":   {Type: "integer"},
+			"num": {Enum: []any{1, "a", true}},
+			"str": {Type: "string", Enum: []any{"1", "2"}},
+		}})
+
+	validate := func(query, body string) []ValidationError {
+		return rn.Validate(httptest.NewRequest("POST", "/x?"+query, strings.NewReader(body)), nil)
+	}
+	for _, tc := range []struct {
+		query, body string
+		errs        []string
+	}{
+		{"n=1.0", `{"n": 1.0, "num": 1.0, "str": "1"}`, nil},
+		{"n=1e3", `{"n": 1e3, "num": "a"}`, nil},
+		{"", `{"num": true}`, nil},
+		{"n=1.5", `{"n": 1.5}`, []string{"query n must be an integer", "body n must be an integer"}},
+		{"", `{"num": "1", "str": 1}`, []string{"body num must be one of [1 a true]", "body str must be a string"}},
+		{"", `{"num": "true", "str": "3"}`, []string{"body num must be one of [1 a true]", "body str must be one of [1 2]"}},
+	} {
+		var got []string
+		for _, e := range validate(tc.query, tc.body) {
+			got = append(got, e.Error())
+		}
+		if !reflect.DeepEqual(got, tc.errs) {
+			t.Errorf("%s %s: expected %q, got %q", tc.query, tc.body, tc.errs, got)
+		}
+	}
+}
+
+type hotBody struct{ A, B int }
+
+func TestRouterHotUpdates(t *testing.T) {
+	var (
+		r    = New(nil)
+		ok   = func(w http.ResponseWriter, req *http.Request, p Params) { w.WriteHeader(http.StatusOK) }
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	base := r.AddRoute("", "GET", "/base/:id", ok)
+	base.WithDoc("base", true)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest("GET", "/base/1", nil))
+				if w.Code != http.StatusOK {
+					t.Errorf("unexpected status: %d", w.Code)
+					return
+				}
+				r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/plugin/1/x/y", nil))
+				r.Allowed("/plugin/2/x")
+				if errs := base.Validate(httptest.NewRequest("GET", "/base/1", nil), Params{{"id", "1"}}); len(errs) > 0 {
+					t.Errorf("unexpected errors: %v", errs)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		p := "/plugin/" + strconv.Itoa(i)
+		r.AddRoute("", "GET", p+"/:a/:b", ok).WithDoc("plugin", true)
+		r.AddRoute("", "POST", p+"/*fp", ok).Doc().WithBodySchema("application/json", r.SchemaOf(reflect.TypeFor[hotBody]()))
+		r.DisableRoute("GET", p+"/:a/:b", i%2 == 0)
+		if i%3 == 0 {
+			r.RemoveRoute("POST", p+"/*fp")
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	// 67 removed, 100 disabled
+	if n := len(r.GetRoutes()); n != 1+200+200-67-100 {
+		t.Fatalf("unexpected number of routes: %d", n)
+	}
+}
+
+func TestRouterDocWhileValidating(t *testing.T) {
+	var (
+		r       = New(nil)
+		wg      sync.WaitGroup
+		started sync.WaitGroup
+		done    = make(chan struct{})
+	)
+	rn := r.AddRoute("", "POST", "/docs/:id", testHandler)
+	rn.WithDoc("docs", true).AsPublic()
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if j == 1 {
+					started.Done()
+				}
+				req := httptest.NewRequest("POST", "/docs/1?n=1", strings.NewReader(`{"A": 1}`))
+				if errs := rn.Validate(req, Params{{"id", "1"}}); len(errs) > 0 {
+					t.Errorf("unexpected errors: %v", errs)
+					return
+				}
+				if _, err := json.Marshal(r.PublicSwagger()); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	started.Wait()
+	for i := 0; i < 200; i++ {
+		sr := rn.Doc().WithTags(strconv.Itoa(i)).WithParam("n", "", "query", "integer", false, nil)
+		sr.WithBodySchema("application/json", r.SchemaOf(reflect.TypeFor[hotBody]())).
+			WithResponseSchema("200", "OK", "application/json", r.SchemaOf(reflect.TypeFor[hotBody]())).
+			WithExample(strconv.Itoa(i), &SwaggerDesc{Value: i})
+	}
+	close(done)
+	wg.Wait()
+
+	if sr := rn.Doc(); len(sr.Tags) != 200 || len(sr.Parameters) != 201 || len(sr.Examples) != 200 || sr.RequestBody == nil {
+		t.Fatalf("unexpected doc: %d tags, %d params, %d examples", len(sr.Tags), len(sr.Parameters), len(sr.Examples))
+	}
+}
+
+func BenchmarkRouterMatch(b *testing.B) {
+	r := buildAPIRouter(b, false)
+	paths := []string{"/campaignReport/1/2/3/4/f.csv", "/dashboard", "/users/10", "/reporting/1/2/3", "/signUp/advertiser", "/"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, p := r.match("GET", paths[i%len(paths)])
+		r.putParams(p)
+	}
+}
+
 func BenchmarkRouter5Params(b *testing.B) {
 	req, _ := http.NewRequest("GET", "/campaignReport/:id/:cid/:start-date/:end-date/:filename", nil)
 	r := buildAPIRouter(b, false)
@@ -86,3 +1230,24 @@ func buildAPIRouter(tb testing.TB, print bool) (r *Router) {
 	}
 	return r
 }
+
+func TestApplyValidateTag(t *testing.T) {
+	var r Router
+	s := r.SchemaOf(reflect.TypeFor[[]int]())
+	if !s.ApplyValidateTag("required,min=1,dive,oneof=1 2,max=2") {
+		t.Fatal("expected required")
+	}
+	j, _ := json.Marshal(s)
+	if exp := `{"type":"array","minItems":1,"items":{"type":"integer","format":"int64","enum":[1,2],"maximum":2}}`; string(j) != exp {
+		t.Fatalf("expected %s, got %s", exp, j)
+	}
+
+	s = r.SchemaOf(reflect.TypeFor[string]())
+	if s.ApplyValidateTag("omitempty,len=3,email") {
+		t.Fatal("unexpected required")
+	}
+	j, _ = json.Marshal(s)
+	if exp := `{"type":"string","format":"email","minLength":3,"maxLength":3}`; string(j) != exp {
+		t.Fatalf("expected %s, got %s", exp, j)
+	}
+}
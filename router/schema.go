@@ -0,0 +1,297 @@
+package router
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// paramTags are the struct tags of fields bound from request params instead of the body.
+var paramTags = [...]string{"path", "query", "header", "cookie", "form"}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// SchemaOf returns the JSON schema of t, named struct types are added to the document's components/schemas
+// and referenced, ex: {"$ref": "#/components/schemas/User"}, a nil t is any value.
+//
+// Schemas follow encoding/json: json tags rename or skip fields, only fields with a validate:"required" tag are required,
+// embedded structs are flattened, time.Time is a date-time string, []byte a base64 string and a map an object.
+// Fields tagged with path, query, header, cookie or form and no json tag are skipped, they're bound from other parts
+// of the request, ex: by gserv.Context.BindAll, and validate tags add their constraints, see SwaggerDefinition.ApplyValidateTag.
+func (r *Router) SchemaOf(t reflect.Type) *SwaggerDefinition {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	def := r.swagger.schemaOf(t)
+	r.storeSchemas()
+	return def
+}
+
+func (s *Swagger) schemaOf(t reflect.Type) *SwaggerDefinition {
+	if t == nil {
+		return &SwaggerDefinition{}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch pt := reflect.PointerTo(t); {
+	case t == timeType:
+		return &SwaggerDefinition{Type: "string", Format: "date-time"}
+	case t.Implements(jsonMarshalerType) || pt.Implements(jsonMarshalerType):
+		// can be anything
+		return &SwaggerDefinition{}
+	case t.Implements(textMarshalerType) || pt.Implements(textMarshalerType):
+		return &SwaggerDefinition{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &SwaggerDefinition{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &SwaggerDefinition{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &SwaggerDefinition{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &SwaggerDefinition{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &SwaggerDefinition{Type: "number", Format: "double"}
+	case reflect.String:
+		return &SwaggerDefinition{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &SwaggerDefinition{Type: "string", ContentEncoding: "base64"}
+		}
+		return &SwaggerDefinition{Type: "array", Items: s.schemaOf(t.Elem())}
+	case reflect.Array:
+		return &SwaggerDefinition{Type: "array", Items: s.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &SwaggerDefinition{Type: "object", AdditionalProperties: s.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		return &SwaggerDefinition{Ref: "#/components/schemas/" + s.componentName(t)}
+	default:
+		return &SwaggerDefinition{}
+	}
+}
+
+// componentName returns the name of the named struct type t under components/schemas, adding its schema if needed.
+func (s *Swagger) componentName(t reflect.Type) string {
+	if name, ok := s.schemaNames[t]; ok {
+		return name
+	}
+
+	if s.Components == nil {
+		s.Components = &SwaggerComponents{}
+	}
+	if s.Components.Schemas == nil {
+		s.Components.Schemas = map[string]*SwaggerDefinition{}
+	}
+	if s.schemaNames == nil {
+		s.schemaNames = map[reflect.Type]string{}
+	}
+
+	base := schemaName(t)
+	name := base
+	for i := 2; s.Components.Schemas[name] != nil; i++ {
+		name = base + strconv.Itoa(i)
+	}
+
+	// added before its fields, so recursive types reference it
+	def := &SwaggerDefinition{}
+	s.schemaNames[t], s.Components.Schemas[name] = name, def
+	*def = *s.structSchema(t)
+	return name
+}
+
+// schemaName returns the name of t without package paths, in the ^[a-zA-Z0-9._-]+$ form OpenAPI requires
+// for component names, ex: GenResponse[go.oneofone.dev/gserv.JSONCodec] -> GenResponse_JSONCodec.
+func schemaName(t reflect.Type) string {
+	parts := strings.FieldsFunc(t.Name(), func(c rune) bool {
+		return strings.ContainsRune("[]*, ", c)
+	})
+	for i, p := range parts {
+		if j := strings.LastIndexByte(p, '/'); j > -1 {
+			p = p[j+1:]
+		}
+		if j := strings.LastIndexByte(p, '.'); j > -1 {
+			p = p[j+1:]
+		}
+		parts[i] = p
+	}
+	return strings.Join(parts, "_")
+}
+
+func (s *Swagger) structSchema(t reflect.Type) *SwaggerDefinition {
+	var fs structFields
+	fs.add(s, t, 0, false)
+
+	def := &SwaggerDefinition{Type: "object"}
+	if len(fs.names) == 0 {
+		return def
+	}
+
+	props := make(map[string]*SwaggerDefinition, len(fs.names))
+	for _, name := range fs.names {
+		f := fs.fields[name]
+		props[name] = f.schema
+		if f.required {
+			def.Required = append(def.Required, name)
+		}
+	}
+	def.Properties = props
+	return def
+}
+
+type structField struct {
+	schema   *SwaggerDefinition
+	depth    int
+	required bool
+}
+
+// structFields collects the fields of a struct in order, the least nested field wins if embedded structs have the same field,
+// like encoding/json.
+type structFields struct {
+	names  []string
+	fields map[string]structField
+}
+
+func (fs *structFields) add(s *Swagger, t reflect.Type, depth int, optional bool) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag, hasTag := f.Tag.Lookup("json")
+		if tag == "-" || !hasTag && isParamField(f) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			isPtr := ft.Kind() == reflect.Pointer
+			if isPtr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fs.add(s, ft, depth+1, optional || isPtr)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		if ex, ok := fs.fields[name]; ok && ex.depth <= depth {
+			continue
+		} else if !ok {
+			fs.names = append(fs.names, name)
+		}
+
+		sf := structField{schema: s.schemaOf(ft), depth: depth}
+		for opt := range strings.SplitSeq(opts, ",") {
+			if sc := sf.schema; opt == "string" && (sc.Type == "integer" || sc.Type == "number" || sc.Type == "boolean") {
+				sf.schema = &SwaggerDefinition{Type: "string"}
+			}
+		}
+		// encoding/json accepts missing fields, so only the ones gserv.Validate requires are
+		if vt := f.Tag.Get("validate"); vt != "" && sf.schema.ApplyValidateTag(vt) {
+			sf.required = !optional
+		}
+
+		if fs.fields == nil {
+			fs.fields = map[string]structField{}
+		}
+		fs.fields[name] = sf
+	}
+}
+
+// ApplyValidateTag adds the constraints of a validate struct tag, ex: `validate:"required,min=1,max=64"`, to s,
+// and reports whether it has the required rule, see gserv.Validate.
+// min, max and len are the minimum and maximum of numbers, or the lengths of strings and arrays, oneof is an enum,
+// email and url are formats, and the rules after dive apply to the items of arrays or the values of maps.
+func (s *SwaggerDefinition) ApplyValidateTag(tag string) (required bool) {
+	cur := s
+	for r := range strings.SplitSeq(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(r), "=")
+		switch name {
+		case "required":
+			if cur == s {
+				required = true
+			}
+		case "dive":
+			items := cur.Items
+			if cur.Type == "object" {
+				items = cur.AdditionalProperties
+			}
+			if cur = asSchema(items); cur == nil {
+				return required
+			}
+		case "min", "max", "len":
+			cur.applyBound(name, arg)
+		case "oneof":
+			for _, v := range strings.Fields(arg) {
+				if cur.Type == "integer" || cur.Type == "number" {
+					cur.Enum = append(cur.Enum, json.Number(v))
+				} else {
+					cur.Enum = append(cur.Enum, v)
+				}
+			}
+		case "email":
+			cur.Format = "email"
+		case "url":
+			cur.Format = "uri"
+		}
+	}
+	return required
+}
+
+func (s *SwaggerDefinition) applyBound(name, arg string) {
+	f, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return
+	}
+	n := int(f)
+	switch s.Type {
+	case "integer", "number":
+		if name != "max" {
+			s.Minimum = &f
+		}
+		if name != "min" {
+			s.Maximum = &f
+		}
+	case "string":
+		if name != "max" {
+			s.MinLength = &n
+		}
+		if name != "min" {
+			s.MaxLength = &n
+		}
+	case "array":
+		if name != "max" {
+			s.MinItems = &n
+		}
+		if name != "min" {
+			s.MaxItems = &n
+		}
+	}
+}
+
+func isParamField(f reflect.StructField) bool {
+	for _, tag := range paramTags {
+		if _, ok := f.Tag.Lookup(tag); ok {
+			return true
+		}
+	}
+	return false
+}
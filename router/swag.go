@@ -2,15 +2,31 @@ package router
 
 import (
 	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
 	"strings"
 )
 
+// Swagger is an OpenAPI 3.1 document, route patterns are converted to OpenAPI paths when it's marshaled,
+// ex: /users/:id<int> -> /users/{id}, and a route with optional params is listed under each of its paths.
 type Swagger struct {
-	OpenAPI     string                        `json:"openapi,omitempty" yaml:"openapi,omitempty"`
-	Server      []SwaggerServer               `json:"server,omitempty" yaml:"server,omitempty"`
-	Info        *SwaggerInfo                  `json:"info,omitempty" yaml:"info,omitempty"`
-	Paths       SwaggerPath                   `json:"paths,omitempty" yaml:"paths,omitempty"`
+	OpenAPI    string             `json:"openapi,omitempty" yaml:"openapi,omitempty"`
+	Server     []SwaggerServer    `json:"servers,omitempty" yaml:"servers,omitempty"`
+	Info       *SwaggerInfo       `json:"info,omitempty" yaml:"info,omitempty"`
+	Paths      SwaggerPath        `json:"paths,omitempty" yaml:"paths,omitempty"`
+	Components *SwaggerComponents `json:"components,omitempty" yaml:"components,omitempty"`
+
+	// Deprecated: Swagger 2 style, use Components.Schemas, it's merged into them when marshaled.
 	Definitions map[string]*SwaggerDefinition `json:"definitions,omitempty" yaml:"definitions,omitempty"`
+
+	schemaNames map[reflect.Type]string // see SchemaOf
+}
+
+// SwaggerComponents holds the reusable schemas of the document, see Router.SchemaOf.
+type SwaggerComponents struct {
+	Schemas map[string]*SwaggerDefinition `json:"schemas,omitempty" yaml:"schemas,omitempty"`
 }
 
 type SwaggerInfo struct {
@@ -38,11 +54,27 @@ type SwaggerParam struct {
 	AllowEmptyValue bool               `json:"allowEmptyValue,omitempty" yaml:"allowEmptyValue,omitempty"`
 }
 
+// SwaggerDefinition is a JSON schema, Properties, Items and AdditionalProperties are map[string]*SwaggerDefinition
+// and *SwaggerDefinition when generated by Router.SchemaOf.
 type SwaggerDefinition struct {
-	Type       string   `json:"type,omitempty"`
-	Required   []string `json:"required,omitempty"`
-	Properties any      `json:"properties,omitempty"`
-	Items      any      `json:"items,omitempty"`
+	Ref                  string               `json:"$ref,omitempty"`
+	AllOf                []*SwaggerDefinition `json:"allOf,omitempty"`
+	Type                 string               `json:"type,omitempty"`
+	Format               string               `json:"format,omitempty"`
+	ContentEncoding      string               `json:"contentEncoding,omitempty"`
+	Pattern              string               `json:"pattern,omitempty"`
+	Enum                 []any                `json:"enum,omitempty"`
+	Minimum              *float64             `json:"minimum,omitempty"`
+	Maximum              *float64             `json:"maximum,omitempty"`
+	MinLength            *int                 `json:"minLength,omitempty"`
+	MaxLength            *int                 `json:"maxLength,omitempty"`
+	MinItems             *int                 `json:"minItems,omitempty"`
+	MaxItems             *int                 `json:"maxItems,omitempty"`
+	Description          string               `json:"description,omitempty"`
+	Required             []string             `json:"required,omitempty"`
+	Properties           any                  `json:"properties,omitempty"`
+	AdditionalProperties any                  `json:"additionalProperties,omitempty"`
+	Items                any                  `json:"items,omitempty"`
 }
 
 type SwaggerDefinitionField struct {
@@ -59,18 +91,19 @@ type SwaggerDesc struct {
 }
 
 type SwaggerRequestBodyContent struct {
+	Schema   *SwaggerDefinition      `json:"schema,omitempty" yaml:"schema,omitempty"`
 	Example  string                  `json:"example,omitempty" yaml:"example,omitempty"`
 	Examples map[string]*SwaggerDesc `json:"examples,omitempty" yaml:"examples,omitempty"`
 }
 
 type SwaggerRequestBody struct {
 	Description string                                `json:"description,omitempty" yaml:"description,omitempty"`
-	Content     map[string]*SwaggerRequestBodyContent `json:"content,omitempty" yaml:"value,omitempty"`
+	Content     map[string]*SwaggerRequestBodyContent `json:"content,omitempty" yaml:"content,omitempty"`
 	Required    bool                                  `json:"required,omitempty" yaml:"required,omitempty"`
 }
 
 type SwaggerRoute struct {
-	OperationID string          `json:"operationID,omitempty" yaml:"operationID,omitempty"`
+	OperationID string          `json:"operationId,omitempty" yaml:"operationId,omitempty"`
 	Summary     string          `json:"summary,omitempty" yaml:"summary,omitempty"`
 	Description string          `json:"description,omitempty" yaml:"description,omitempty"`
 	Tags        []string        `json:"tags,omitempty" yaml:"tags,omitempty"`
@@ -79,75 +112,136 @@ type SwaggerRoute struct {
 	RequestBody *SwaggerRequestBody     `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
 	Responses   map[string]*SwaggerDesc `json:"responses,omitempty" yaml:"responses,omitempty"`
 	Examples    map[string]*SwaggerDesc `json:"examples,omitempty" yaml:"examples,omitempty"`
-	Public      bool                    `json:"public,omitempty" yaml:"public,omitempty"`
+
+	// Public isn't part of the serialized document since OpenAPI 3.1 operations can't carry it,
+	// use Router.PublicSwagger to get the public routes.
+	Public bool `json:"-" yaml:"-"`
+
+	rn *Route // the documented route, see update
 }
 
 func (sr *SwaggerRoute) WithOperationID(v string) *SwaggerRoute {
-	sr.OperationID = v
-	return sr
+	return sr.update(func(sr *SwaggerRoute) { sr.OperationID = v })
 }
 
 func (sr *SwaggerRoute) WithSummary(v string) *SwaggerRoute {
-	sr.Summary = v
-	return sr
+	return sr.update(func(sr *SwaggerRoute) { sr.Summary = v })
 }
 
 func (sr *SwaggerRoute) WithDescription(v string) *SwaggerRoute {
-	sr.Description = v
-	return sr
+	return sr.update(func(sr *SwaggerRoute) { sr.Description = v })
 }
 
 func (sr *SwaggerRoute) WithTags(v ...string) *SwaggerRoute {
-	sr.Tags = append(sr.Tags, v...)
-	return sr
+	return sr.update(func(sr *SwaggerRoute) { sr.Tags = append(slices.Clip(sr.Tags), v...) })
 }
 
 func (sr *SwaggerRoute) WithBody(contentType string, example any) *SwaggerRoute {
-	if sr.RequestBody == nil {
-		sr.RequestBody = &SwaggerRequestBody{}
-	}
-	rbc := sr.RequestBody.Content
-	if rbc == nil {
-		rbc = map[string]*SwaggerRequestBodyContent{}
-		sr.RequestBody.Content = rbc
-	}
-	v := rbc[contentType]
-	if v == nil {
-		v = &SwaggerRequestBodyContent{}
-		rbc[contentType] = v
-	}
-	switch ex := example.(type) {
+	var ex string
+	switch v := example.(type) {
 	case string:
-		v.Example = ex
+		ex = v
 	default:
-		b, err := json.MarshalIndent(ex, "", "\t")
+		b, err := json.MarshalIndent(v, "", "\t")
 		if err != nil {
 			panic(err)
 		}
-		v.Example = string(b)
+		ex = string(b)
 	}
-	return sr
+	return sr.update(func(sr *SwaggerRoute) { sr.bodyContent(contentType).Example = ex })
 }
 
-func (sr *SwaggerRoute) WithResponse(name string, ex *SwaggerDesc) *SwaggerRoute {
-	if sr.Responses == nil {
-		sr.Responses = map[string]*SwaggerDesc{}
+// WithBodySchema sets the schema of the request body of contentType, see Router.SchemaOf.
+func (sr *SwaggerRoute) WithBodySchema(contentType string, schema *SwaggerDefinition) *SwaggerRoute {
+	return sr.update(func(sr *SwaggerRoute) { sr.bodyContent(contentType).Schema = schema })
+}
+
+// bodyContent returns a copy of the request body content of contentType, sr's request body is replaced by a copy holding it.
+func (sr *SwaggerRoute) bodyContent(contentType string) *SwaggerRequestBodyContent {
+	rb := &SwaggerRequestBody{}
+	if sr.RequestBody != nil {
+		*rb = *sr.RequestBody
 	}
-	sr.Responses[name] = ex
-	return sr
+	if rb.Content = maps.Clone(rb.Content); rb.Content == nil {
+		rb.Content = map[string]*SwaggerRequestBodyContent{}
+	}
+	v := &SwaggerRequestBodyContent{}
+	if old := rb.Content[contentType]; old != nil {
+		*v = *old
+	}
+	rb.Content[contentType] = v
+	sr.RequestBody = rb
+	return v
 }
 
-func (sr *SwaggerRoute) WithExample(name string, ex *SwaggerDesc) *SwaggerRoute {
-	if sr.Examples == nil {
-		sr.Examples = map[string]*SwaggerDesc{}
+// WithResponseSchema documents the response for status, ex: "200" or "default", as contentType with schema, see Router.SchemaOf.
+func (sr *SwaggerRoute) WithResponseSchema(status, description, contentType string, schema *SwaggerDefinition) *SwaggerRoute {
+	return sr.update(func(sr *SwaggerRoute) {
+		if sr.Responses = maps.Clone(sr.Responses); sr.Responses == nil {
+			sr.Responses = map[string]*SwaggerDesc{}
+		}
+		resp := &SwaggerDesc{}
+		if old := sr.Responses[status]; old != nil {
+			*resp = *old
+		}
+		resp.Description = description
+
+		content, _ := resp.Content.(map[string]*SwaggerRequestBodyContent)
+		if content = maps.Clone(content); content == nil {
+			content = map[string]*SwaggerRequestBodyContent{}
+		}
+		content[contentType] = &SwaggerRequestBodyContent{Schema: schema}
+		resp.Content = content
+		sr.Responses[status] = resp
+	})
+}
+
+// forPath returns sr with only the path params in names, all of them required as OpenAPI wants, sr is copied if it has to change.
+func (sr *SwaggerRoute) forPath(names []string) *SwaggerRoute {
+	changed := false
+	params := make([]*SwaggerParam, 0, len(sr.Parameters))
+	for _, p := range sr.Parameters {
+		if p.In == "path" {
+			if !slices.Contains(names, p.Name) {
+				changed = true
+				continue
+			}
+			if !p.Required {
+				cp := *p
+				cp.Required, p, changed = true, &cp, true
+			}
+		}
+		params = append(params, p)
 	}
-	sr.Examples[name] = ex
-	return sr
+
+	if !changed {
+		return sr
+	}
+	cp := *sr
+	cp.Parameters = params
+	return &cp
+}
+
+func (sr *SwaggerRoute) WithResponse(name string, ex *SwaggerDesc) *SwaggerRoute {
+	return sr.update(func(sr *SwaggerRoute) {
+		if sr.Responses = maps.Clone(sr.Responses); sr.Responses == nil {
+			sr.Responses = map[string]*SwaggerDesc{}
+		}
+		sr.Responses[name] = ex
+	})
+}
+
+func (sr *SwaggerRoute) WithExample(name string, ex *SwaggerDesc) *SwaggerRoute {
+	return sr.update(func(sr *SwaggerRoute) {
+		if sr.Examples = maps.Clone(sr.Examples); sr.Examples == nil {
+			sr.Examples = map[string]*SwaggerDesc{}
+		}
+		sr.Examples[name] = ex
+	})
 }
 
 func (sr *SwaggerRoute) WithParams(params []*SwaggerParam) *SwaggerRoute {
-	sr.Parameters = append(sr.Parameters, params...)
-	return sr
+	return sr.update(func(sr *SwaggerRoute) { sr.Parameters = append(slices.Clip(sr.Parameters), params...) })
 }
 
 func (sr *SwaggerRoute) WithParam(name, desc, in, typ string, required bool, schema *SwaggerDefinition) *SwaggerRoute {
@@ -165,17 +259,67 @@ func (sr *SwaggerRoute) WithParam(name, desc, in, typ string, required bool, sch
 	}
 	p.Schema.Type = typ
 
-	sr.Parameters = append(sr.Parameters, &p)
-	return sr
+	return sr.WithParams([]*SwaggerParam{&p})
+}
+
+// WithParamSchema sets the schema of the documented param name in in, ex: "path", if there's one.
+func (sr *SwaggerRoute) WithParamSchema(name, in string, schema *SwaggerDefinition) *SwaggerRoute {
+	return sr.update(func(sr *SwaggerRoute) {
+		i := slices.IndexFunc(sr.Parameters, func(p *SwaggerParam) bool { return p.In == in && p.Name == name })
+		if i == -1 {
+			return
+		}
+		p := *sr.Parameters[i]
+		p.Schema = schema
+		sr.Parameters = slices.Clone(sr.Parameters)
+		sr.Parameters[i] = &p
+	})
 }
 
 // AsPublic designates this route as public documentation.
 func (sr *SwaggerRoute) AsPublic() *SwaggerRoute {
-	sr.Public = true
-	return sr
+	return sr.update(func(sr *SwaggerRoute) { sr.Public = true })
+}
+
+// update applies fn to sr, or if sr documents a route, to a copy of its current documentation that replaces it
+// and is returned, so the documentation readers loaded, ex: Route.Validate, never changes under them.
+func (sr *SwaggerRoute) update(fn func(sr *SwaggerRoute)) *SwaggerRoute {
+	rn := sr.rn
+	if rn == nil {
+		fn(sr)
+		return sr
+	}
+
+	r := rn.r
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	cur := r.routeInfo(rn.m, rn.fp)
+	if cur == nil || cur.rn != rn {
+		// the route's documentation was removed, the copy isn't stored
+		cp := *sr
+		fn(&cp)
+		return &cp
+	}
+	cp := *cur
+	fn(&cp)
+	r.swagger.Paths[rn.fp][openAPIMethod(rn.m)] = &cp
+	rn.doc.Store(&cp)
+	r.storeSchemas()
+	return &cp
 }
 
-func (r *Router) addRouteInfo(method, path string, desc *SwaggerRoute) *SwaggerRoute {
+// addRouteInfo documents rn with desc, which is owned by rn from then on, see SwaggerRoute.update.
+func (r *Router) addRouteInfo(rn *Route, desc *SwaggerRoute) *SwaggerRoute {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.setRouteInfo(rn, desc)
+}
+
+// setRouteInfo is addRouteInfo, must be called with Router.mux held.
+func (r *Router) setRouteInfo(rn *Route, desc *SwaggerRoute) *SwaggerRoute {
+	method, path := rn.m, rn.fp
+
 	p := r.swagger.Paths
 	if p == nil {
 		p = SwaggerPath{}
@@ -191,14 +335,275 @@ func (r *Router) addRouteInfo(method, path string, desc *SwaggerRoute) *SwaggerR
 	if desc == nil {
 		desc = &SwaggerRoute{}
 	}
-	m[strings.ToLower(method)] = desc
+	method = openAPIMethod(method)
+	if old := m[method]; old != nil {
+		// keep the body and responses documented by Route.Doc users, ex: the typed gserv helpers
+		if desc.RequestBody == nil {
+			desc.RequestBody = old.RequestBody
+		}
+		if desc.Responses == nil {
+			desc.Responses = old.Responses
+		}
+	}
+	desc.rn = rn
+	m[method] = desc
+	rn.doc.Store(desc)
+	r.storeSchemas()
 	return desc
 }
 
+// storeSchemas stores a snapshot of the document's schemas for Route.Validate, must be called with Router.mux held.
+func (r *Router) storeSchemas() {
+	schemas := maps.Clone(r.swagger.Definitions)
+	if c := r.swagger.Components; c != nil {
+		if schemas == nil {
+			schemas = maps.Clone(c.Schemas)
+		} else {
+			maps.Copy(schemas, c.Schemas)
+		}
+	}
+	r.schemas.Store(&schemas)
+}
+
+// hasRouteInfo reports whether method and path are documented, must be called with Router.mux held.
+func (r *Router) hasRouteInfo(method, path string) bool {
+	return r.routeInfo(method, path) != nil
+}
+
+// routeInfo returns the documentation of method and path or nil, must be called with Router.mux held.
+func (r *Router) routeInfo(method, path string) *SwaggerRoute {
+	method = openAPIMethod(method)
+	return r.swagger.Paths[path][method]
+}
+
+// removeRouteInfo removes the documentation of method and path, and the path itself if it has no other methods.
+func (r *Router) removeRouteInfo(method, path string) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	m := r.swagger.Paths[path]
+	if m == nil {
+		return
+	}
+	method = openAPIMethod(method)
+	if delete(m, method); len(m) == 0 {
+		delete(r.swagger.Paths, path)
+	}
+}
+
+// openAPIMethod returns the key of method in SwaggerPath, the lowercase method if OpenAPI has a fixed field for it.
+func openAPIMethod(method string) string {
+	if lm := strings.ToLower(method); isOpenAPIMethod(lm) {
+		return lm
+	}
+	return method
+}
+
+// isOpenAPIMethod reports whether OpenAPI has a fixed path item field for the (lowercase) method.
+func isOpenAPIMethod(method string) bool {
+	switch method {
+	case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+		return true
+	}
+	return false
+}
+
+// MarshalJSON implements json.Marshaler, route patterns are converted to OpenAPI paths, operations of methods
+// OpenAPI doesn't have a fixed field for (ex: PROPFIND, QUERY) are moved under the path's x-additionalOperations
+// extension (additionalOperations is OpenAPI 3.2 only), and Definitions are merged into Components.
+// Operations of different patterns that map to the same OpenAPI path are merged, it's an error if they have the same method,
+// ex: GET /n/:id and GET /n/:id<int> are both GET /n/{id}.
+func (s Swagger) MarshalJSON() ([]byte, error) {
+	type swagger Swagger
+	paths := make(map[string]map[string]any, len(s.Paths))
+	owners := map[[2]string]string{} // OpenAPI path and method -> pattern
+	for _, p := range slices.Sorted(maps.Keys(s.Paths)) {
+		ops := s.Paths[p]
+		oaPaths, params := openAPIPaths(p)
+		for i, op := range oaPaths {
+			m := paths[op]
+			if m == nil {
+				m = make(map[string]any, len(ops))
+				paths[op] = m
+			}
+			for method, sr := range ops {
+				key := [2]string{op, method}
+				if ex, ok := owners[key]; ok {
+					um := strings.ToUpper(method)
+					return nil, fmt.Errorf("router: %s %s and %s %s are both documented as %s %s", um, ex, um, p, um, op)
+				}
+				owners[key] = p
+
+				if params != nil {
+					sr = sr.forPath(params[i])
+				}
+				if isOpenAPIMethod(method) {
+					m[method] = sr
+					continue
+				}
+				extra, _ := m["x-additionalOperations"].(map[string]*SwaggerRoute)
+				if extra == nil {
+					extra = map[string]*SwaggerRoute{}
+					m["x-additionalOperations"] = extra
+				}
+				extra[method] = sr
+			}
+		}
+	}
+
+	comps := s.Components
+	if len(s.Definitions) > 0 {
+		comps = &SwaggerComponents{Schemas: map[string]*SwaggerDefinition{}}
+		maps.Copy(comps.Schemas, s.Definitions)
+		if s.Components != nil {
+			maps.Copy(comps.Schemas, s.Components.Schemas)
+		}
+	}
+
+	info := s.Info
+	if info == nil {
+		// required by OpenAPI
+		info = &SwaggerInfo{Title: "API", Version: "0.0.0"}
+	}
+
+	return json.Marshal(struct {
+		*swagger
+		Info        *SwaggerInfo                  `json:"info"`
+		Paths       map[string]map[string]any     `json:"paths,omitempty"`
+		Components  *SwaggerComponents            `json:"components,omitempty"`
+		Definitions map[string]*SwaggerDefinition `json:"definitions,omitempty"`
+	}{(*swagger)(&s), info, paths, comps, nil})
+}
+
+// openAPIPaths returns the OpenAPI paths of the route pattern p, one per optional variant, with the names of their params,
+// ex: /posts/:year<int>/:month? -> [/posts/{year}/{month} /posts/{year}], [[year month] [year]].
+// Paths that aren't valid patterns, ex: written by hand, are returned as is.
+func openAPIPaths(p string) (paths []string, params [][]string) {
+	if n := len(p) - 1; n > 0 && p[n] == '/' {
+		p = p[:n]
+	}
+	segs, _, err := parsePattern(p)
+	if err != nil {
+		return []string{p}, nil
+	}
+	variants, err := expandOptional(segs)
+	if err != nil {
+		return []string{p}, nil
+	}
+
+	for _, segs := range variants {
+		var (
+			sb    strings.Builder
+			names []string
+		)
+		for _, s := range segs {
+			if s.kind == segStatic {
+				sb.WriteString(s.val)
+				continue
+			}
+			sb.WriteString("{" + s.val + "}")
+			names = append(names, s.val)
+		}
+		if sb.Len() == 0 {
+			sb.WriteByte('/')
+		}
+		paths, params = append(paths, sb.String()), append(params, names)
+	}
+	return paths, params
+}
+
 func (r *Router) Swagger() *Swagger {
 	return &r.swagger
 }
 
+// PublicSwagger returns a copy of the document with only the routes documented as public, see SwaggerRoute.AsPublic,
+// and the component schemas they use.
+func (r *Router) PublicSwagger() *Swagger {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	s := r.swagger
+	s.Paths, s.schemaNames = SwaggerPath{}, nil
+
+	var (
+		used  = map[string]bool{}
+		queue []string
+	)
+	addRefs := func(v any) {
+		schemaRefs(v, func(name string) {
+			if !used[name] {
+				used[name] = true
+				queue = append(queue, name)
+			}
+		})
+	}
+
+	for p, ops := range r.swagger.Paths {
+		for method, sr := range ops {
+			if !sr.Public {
+				continue
+			}
+			if s.Paths[p] == nil {
+				s.Paths[p] = map[string]*SwaggerRoute{}
+			}
+			s.Paths[p][method] = sr
+
+			for _, param := range sr.Parameters {
+				addRefs(param.Schema)
+			}
+			if sr.RequestBody != nil {
+				for _, c := range sr.RequestBody.Content {
+					addRefs(c.Schema)
+				}
+			}
+			for _, resp := range sr.Responses {
+				content, _ := resp.Content.(map[string]*SwaggerRequestBodyContent)
+				for _, c := range content {
+					addRefs(c.Schema)
+				}
+			}
+		}
+	}
+
+	if r.swagger.Components == nil {
+		return &s
+	}
+	all := r.swagger.Components.Schemas
+	s.Components = &SwaggerComponents{Schemas: map[string]*SwaggerDefinition{}}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if def := all[name]; def != nil {
+			s.Components.Schemas[name] = def
+			addRefs(def)
+		}
+	}
+	return &s
+}
+
+// schemaRefs calls fn with the name of every component schema v references, v is a schema or a collection of them.
+func schemaRefs(v any, fn func(name string)) {
+	switch v := v.(type) {
+	case *SwaggerDefinition:
+		if v == nil {
+			return
+		}
+		if name, ok := strings.CutPrefix(v.Ref, "#/components/schemas/"); ok {
+			fn(name)
+		}
+		for _, d := range v.AllOf {
+			schemaRefs(d, fn)
+		}
+		schemaRefs(v.Properties, fn)
+		schemaRefs(v.Items, fn)
+		schemaRefs(v.AdditionalProperties, fn)
+	case map[string]*SwaggerDefinition:
+		for _, d := range v {
+			schemaRefs(d, fn)
+		}
+	}
+}
+
 /*
 type AutoGenerated struct {
 	Openapi    string     `json:"openapi" yaml:"openapi"`
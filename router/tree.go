@@ -0,0 +1,486 @@
+package router
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// segment kinds of a parsed route pattern
+const (
+	segStatic uint8 = iota
+	segParam
+	segStar
+)
+
+// segment is a single part of a parsed route pattern, either a literal string or a named param.
+type segment struct {
+	val      string      // the literal text for static segments, the param name otherwise
+	cons     *constraint // optional :param constraint
+	kind     uint8
+	optional bool // :param?
+}
+
+// parsePattern splits a route pattern (ex: /api/v1/users/:id<int>/:name.:ext?) into its static and param segments:
+//
+//	segs -> [{/api/v1/users/} {id<int> ':'} {/} {name ':'} {.} {ext ':'?}]
+//	stars -> number of stars, basically a sanity check, if it's not 0 or 1 then it's an invalid path
+//	err -> returned if a param constraint is invalid or two params aren't separated by a literal
+//
+// A :param name is made of letters, digits, '_' and '-', anything else ends it,
+// so multiple params can share a segment, ex: /files/:name.:ext.
+func parsePattern(p string) (segs []segment, stars int, err error) {
+	for p != "" {
+		i := strings.IndexAny(p, ":*")
+		if i == -1 {
+			segs = append(segs, segment{val: p})
+			break
+		}
+
+		if i > 0 {
+			segs = append(segs, segment{val: p[:i]})
+		} else if len(segs) > 0 {
+			return nil, stars, fmt.Errorf("params must be separated by a literal in %q", p)
+		}
+
+		if p[i] == '*' {
+			stars++
+			p = p[i+1:]
+			end := strings.IndexByte(p, '/')
+			if end == -1 {
+				end = len(p)
+			}
+			segs = append(segs, segment{val: p[:end], kind: segStar})
+			p = p[end:]
+			continue
+		}
+
+		p = p[i+1:]
+		end := 0
+		for end < len(p) && isParamNameChar(p[end]) {
+			end++
+		}
+
+		s := segment{val: p[:end], kind: segParam}
+		if end < len(p) && p[end] == '<' {
+			gt := constraintEnd(p, end)
+			if gt == -1 {
+				return nil, stars, fmt.Errorf("unterminated param constraint in %q", p)
+			}
+			if s.cons, err = newConstraint(p[end+1 : gt]); err != nil {
+				return nil, stars, err
+			}
+			end = gt + 1
+		}
+
+		if end < len(p) && p[end] == '?' {
+			s.optional = true
+			end++
+		}
+
+		segs = append(segs, s)
+		p = p[end:]
+	}
+	return segs, stars, nil
+}
+
+func isParamNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// expandOptional returns all the variants of segs, starting with segs itself, then with the trailing
+// optional params dropped one by one, along with the separator before each of them, ex:
+//
+//	/posts/:year/:month? -> /posts/:year/:month, /posts/:year
+//	/files/:name.:ext? -> /files/:name.:ext, /files/:name
+func expandOptional(segs []segment) (out [][]segment, err error) {
+	out = append(out, segs)
+	for cur := segs; len(cur) > 0 && cur[len(cur)-1].optional; {
+		cur = append([]segment(nil), cur[:len(cur)-1]...)
+		if l := len(cur) - 1; l > -1 && cur[l].kind == segStatic {
+			if v := cur[l].val; (len(cur) > 1 || len(v) > 1) && !isParamNameChar(v[len(v)-1]) {
+				if cur[l].val = v[:len(v)-1]; cur[l].val == "" {
+					cur = cur[:l]
+				}
+			}
+		}
+		out = append(out, cur)
+	}
+
+	for _, s := range out[len(out)-1] {
+		if s.optional {
+			return nil, fmt.Errorf("optional param %s must be at the end of the path", s)
+		}
+	}
+	return out, nil
+}
+
+// constraintEnd returns the index of the '>' closing the '<' at p[start], or -1, nested <> pairs are skipped.
+func constraintEnd(p string, start int) int {
+	depth := 0
+	for i := start; i < len(p); i++ {
+		switch p[i] {
+		case '\\':
+			i++
+		case '<':
+			depth++
+		case '>':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func (s segment) String() string {
+	switch s.kind {
+	case segParam:
+		opt := ""
+		if s.optional {
+			opt = "?"
+		}
+		if s.cons != nil {
+			return fmt.Sprintf("{%s<%s>%s ':'}", s.val, s.cons.name, opt)
+		}
+		return fmt.Sprintf("{%s%s ':'}", s.val, opt)
+	case segStar:
+		return fmt.Sprintf("{%s '*'}", s.val)
+	}
+	return fmt.Sprintf("{%s}", s.val)
+}
+
+// tree is the route table of a single method.
+type tree struct {
+	root   node
+	static map[string]*Route // routes without any params, for the fast path
+}
+
+// clone returns a copy of t that can be changed without affecting t, t can be nil.
+func (t *tree) clone() *tree {
+	if t == nil {
+		return &tree{static: map[string]*Route{}}
+	}
+	cp := &tree{root: *t.root.clone(), static: t.static}
+	return cp
+}
+
+// insert adds the route to the tree and returns it, or returns an already existing route for the same pattern.
+// t must be a clone, the nodes on the route's path are copied before they're changed.
+func (t *tree) insert(segs []segment, rn *Route) *Route {
+	n := t.root.walk(segs, true)
+	if n.route != nil {
+		return n.route
+	}
+	n.route = rn
+
+	if len(segs) == 1 && segs[0].kind == segStatic {
+		t.static = maps.Clone(t.static)
+		t.static[segs[0].val] = rn
+	}
+	return rn
+}
+
+// remove removes rn from the tree if it's registered for segs, t must be a clone.
+// Nodes left without routes are kept, lookups just pass through them.
+func (t *tree) remove(segs []segment, rn *Route) {
+	if n := t.root.walk(segs, false); n != nil && n.route == rn {
+		n.route = nil
+	}
+
+	if len(segs) == 1 && segs[0].kind == segStatic && t.static[segs[0].val] == rn {
+		t.static = maps.Clone(t.static)
+		delete(t.static, segs[0].val)
+	}
+}
+
+// node is a node in a compressed radix tree, each method has its own tree.
+//
+// Static children are indexed by the first byte of their prefix, a node can also have
+// :param children, which match one path segment each, constrained ones are tried first,
+// and a single *star child, which matches the rest of the path.
+type node struct {
+	prefix   string
+	indices  string
+	children []*node
+	params   []*node
+	star     *node
+	route    *Route
+	cons     *constraint // only set on :param nodes
+	inSeg    bool        // a :param node followed by a literal in the same segment
+}
+
+// clone returns a shallow copy of n with its own child slices, so it can be changed without affecting n.
+func (n *node) clone() *node {
+	cp := *n
+	cp.children = slices.Clone(n.children)
+	cp.params = slices.Clone(n.params)
+	return &cp
+}
+
+// walk returns the node for segs, copying every node on the way, n must be a copy already.
+// If create is false it returns nil instead of adding missing nodes.
+func (n *node) walk(segs []segment, create bool) *node {
+	for i, s := range segs {
+		switch s.kind {
+		case segStatic:
+			if create && i > 0 && segs[i-1].kind == segParam && s.val[0] != '/' {
+				// the param shares its segment with a literal, ex: /files/:name.:ext
+				n.inSeg = true
+			}
+			n = n.addStatic(s.val, create)
+		case segParam:
+			n = n.addParam(s.cons, create)
+		case segStar:
+			switch {
+			case n.star != nil:
+				n.star = n.star.clone()
+			case create:
+				n.star = &node{}
+			}
+			n = n.star
+		}
+		if n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// addStatic walks (and splits if needed) the static children of n to add the literal s and returns the last node.
+// If create is false it returns nil if s doesn't end on an existing node.
+func (n *node) addStatic(s string, create bool) *node {
+	for s != "" {
+		i := strings.IndexByte(n.indices, s[0])
+		if i == -1 {
+			if !create {
+				return nil
+			}
+			c := &node{prefix: s}
+			n.indices += s[:1]
+			n.children = append(n.children, c)
+			return c
+		}
+
+		c := n.children[i].clone()
+		n.children[i] = c
+		l := commonPrefixLen(c.prefix, s)
+		if l < len(c.prefix) {
+			if !create {
+				return nil
+			}
+			split := *c
+			split.prefix = c.prefix[l:]
+			*c = node{prefix: c.prefix[:l], indices: split.prefix[:1], children: []*node{&split}}
+		}
+		n, s = c, s[l:]
+	}
+	return n
+}
+
+// addParam returns a copy of the :param child of n with the same constraint, adding it if needed.
+// If create is false it returns nil if there's no such child.
+// Constraints are compared by name only, overlapping ones get their own children and are tried in the order they were added.
+func (n *node) addParam(cons *constraint, create bool) *node {
+	for i, c := range n.params {
+		if c.cons == cons || c.cons != nil && cons != nil && c.cons.name == cons.name {
+			c = c.clone()
+			n.params[i] = c
+			return c
+		}
+	}
+
+	if !create {
+		return nil
+	}
+
+	c := &node{cons: cons}
+	if cons == nil {
+		n.params = append(n.params, c)
+		return c
+	}
+
+	// constrained params go before the unconstrained one
+	i := len(n.params)
+	if i > 0 && n.params[i-1].cons == nil {
+		i--
+	}
+	n.params = slices.Insert(n.params, i, c)
+	return c
+}
+
+// find returns the best matching route for path, path is what's left after n's own prefix.
+//
+// The precedence at every node is static > :param<constraint> > :param > *star, if a branch fails to match the rest of the path,
+// find backtracks and tries the next one, so registration order never matters.
+func (n *node) find(path string, m *matcher) *Route {
+walk:
+	if path == "" {
+		if m.ok(n.route) {
+			return n.route
+		}
+
+		// /files matches /files/*fp with an empty value
+		if c := n.child('/'); c != nil && c.prefix == "/" && c.star != nil && m.ok(c.star.route) {
+			m.push("")
+			return c.star.route
+		}
+	} else if c := n.child(path[0]); c != nil {
+		if strings.HasPrefix(path, c.prefix) {
+			if len(n.params) == 0 && n.star == nil {
+				// nothing to backtrack to
+				n, path = c, path[len(c.prefix):]
+				goto walk
+			}
+			if rn := c.find(path[len(c.prefix):], m); rn != nil {
+				return rn
+			}
+		} else if c.star != nil && m.ok(c.star.route) && len(c.prefix) == len(path)+1 && strings.HasPrefix(c.prefix, path) && c.prefix[len(path)] == '/' {
+			m.push("")
+			return c.star.route
+		}
+	}
+
+	if len(n.params) > 0 && path != "" {
+		end := strings.IndexByte(path, '/')
+		if end == -1 {
+			end = len(path)
+		}
+
+		for _, c := range n.params {
+			// a param sharing its segment tries every split where its literal could follow, longest value first,
+			// then the whole segment, so /files/:name.:ext beats /files/:name for /files/a.json
+			if c.inSeg {
+				for i := end - 1; i > 0; i-- {
+					if c.child(path[i]) == nil {
+						continue
+					}
+					if rn := c.findParam(path, i, m); rn != nil {
+						return rn
+					}
+				}
+			}
+			if rn := c.findParam(path, end, m); rn != nil {
+				return rn
+			}
+		}
+	}
+
+	if c := n.star; c != nil && m.ok(c.route) {
+		m.push(path)
+		return c.route
+	}
+
+	return nil
+}
+
+// findFold is find, ignoring the case of static parts, it returns the matched path as registered,
+// with param values kept as is, appended to buf. It doesn't collect params.
+func (n *node) findFold(path string, m *matcher, buf []byte) ([]byte, *Route) {
+	if path == "" {
+		if m.ok(n.route) {
+			return buf, n.route
+		}
+		if c := n.child('/'); c != nil && c.prefix == "/" && c.star != nil && m.ok(c.star.route) {
+			return buf, c.star.route
+		}
+	}
+
+	for _, c := range n.children {
+		if l := len(c.prefix); l <= len(path) && strings.EqualFold(path[:l], c.prefix) {
+			if b, rn := c.findFold(path[l:], m, append(buf, c.prefix...)); rn != nil {
+				return b, rn
+			}
+		} else if c.star != nil && m.ok(c.star.route) && l == len(path)+1 && c.prefix[l-1] == '/' && strings.EqualFold(path, c.prefix[:l-1]) {
+			return append(buf, c.prefix[:l-1]...), c.star.route
+		}
+	}
+
+	if len(n.params) > 0 && path != "" {
+		end := strings.IndexByte(path, '/')
+		if end == -1 {
+			end = len(path)
+		}
+
+		try := func(c *node, i int) ([]byte, *Route) {
+			if v := path[:i]; v != "" && (c.cons == nil || c.cons.match(v)) {
+				return c.findFold(path[i:], m, append(buf, v...))
+			}
+			return buf, nil
+		}
+
+		// same order as find, the splits of a param sharing its segment first, then the whole segment
+		for _, c := range n.params {
+			if c.inSeg {
+				for i := end - 1; i > 0; i-- {
+					if b, rn := try(c, i); rn != nil {
+						return b, rn
+					}
+				}
+			}
+			if b, rn := try(c, end); rn != nil {
+				return b, rn
+			}
+		}
+	}
+
+	if c := n.star; c != nil && m.ok(c.route) {
+		return append(buf, path...), c.route
+	}
+
+	return buf, nil
+}
+
+// findParam matches path[:i], which can't be empty, as the value of the :param node n and looks up the rest of the path under it.
+func (n *node) findParam(path string, i int, m *matcher) *Route {
+	v := path[:i]
+	if v == "" || n.cons != nil && !n.cons.match(v) {
+		return nil
+	}
+	l := m.push(v)
+	if rn := n.find(path[i:], m); rn != nil {
+		return rn
+	}
+	m.ps.p = m.ps.p[:l]
+	return nil
+}
+
+// matcher holds the state of a single lookup.
+type matcher struct {
+	r   *Router
+	ps  *paramsWrapper
+	all bool // match disabled routes too
+}
+
+// ok reports whether rn can be matched.
+func (m *matcher) ok(rn *Route) bool {
+	return rn != nil && (m.all || !rn.disabled.Load())
+}
+
+// push appends a param value and returns the number of values before it, for backtracking.
+func (m *matcher) push(v string) int {
+	if m.ps == nil {
+		m.ps = m.r.getParams()
+	}
+	l := len(m.ps.p)
+	m.ps.p = append(m.ps.p, Param{Value: v})
+	return l
+}
+
+func (n *node) child(c byte) *node {
+	// a plain loop beats strings.IndexByte for the handful of bytes a node usually has
+	for i := 0; i < len(n.indices); i++ {
+		if n.indices[i] == c {
+			return n.children[i]
+		}
+	}
+	return nil
+}
+
+func commonPrefixLen(a, b string) (i int) {
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
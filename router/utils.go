@@ -1,99 +1,11 @@
 package router
 
 import (
-	"fmt"
 	"io"
 	"net/http"
-	"regexp"
 	"strings"
 )
 
-type nodePart string
-
-func (np nodePart) Name() string { return string(np[1:]) }
-func (np nodePart) Type() uint8  { return np[0] }
-func (np nodePart) String() string {
-	if np.Type() == '/' {
-		return fmt.Sprintf("{%s}", np.Name())
-	}
-	return fmt.Sprintf("{%s '%c'}", np.Name(), np.Type())
-}
-
-var re = regexp.MustCompile(`([:*/]?[^:*]+)`)
-
-// splitPathToParts takes in a path (ex: /api/v1/someEndpoint/:id/*any) and returns:
-//
-//	pp -> the longest part before the first param (/api/v1/someEndpoint/:)
-//	rest -> all the params (id, any)
-//	num -> number of params (probably not needed...)
-//	stars -> number of stars, basically a sanity check, if it's not 0 or 1 then it's an invalid path
-func splitPathToParts(p string) (pp string, rest []nodePart, num, stars int) {
-	parts := re.FindAllString(p, -1)
-	if len(parts) < 2 {
-		pp = p
-		return pp, rest, num, stars
-	}
-
-	pp = parts[0]
-	for _, part := range parts[1:] {
-		splitPathFn(part, '/', func(sp string, _, _ int) bool {
-			switch c := sp[0]; c {
-			case '*':
-				stars++
-				fallthrough
-			case ':':
-				num++
-				fallthrough
-			case '/':
-				rest = append(rest, nodePart(sp))
-			}
-			return false
-		})
-	}
-	return pp, rest, num, stars
-}
-
-func splitPathFn(s string, sep uint8, fn func(p string, pidx, idx int) bool) bool {
-	pi, last := 0, 0
-	for i := 0; i < len(s); i++ {
-		if s[i] != sep {
-			if i < len(s)-1 {
-				continue
-			}
-			i = len(s)
-		}
-
-		if last < i {
-			if fn(s[last:i], pi, i) {
-				return true
-			}
-			last = i
-			pi++
-		}
-	}
-
-	return false
-}
-
-func revSplitPathFn(s string, sep uint8, fn func(p string, pidx, idx int) bool) bool {
-	pi, last := 0, len(s)
-	var ss string
-	for i := len(s) - 1; i > -1; i-- {
-		if s[i] != sep {
-			continue
-		}
-		if ss = s[i:last]; ss != "" {
-			if fn(ss, pi, last) {
-				return true
-			}
-			last = i
-			pi++
-		}
-	}
-
-	return false
-}
-
 type headRW struct {
 	http.ResponseWriter
 }
@@ -226,9 +138,19 @@ func bufApp(buf *[]byte, s string, w int, c byte) {
 	(*buf)[w] = c
 }
 
-var routeCtxKey = struct{ int }{420}
+var (
+	routeCtxKey   = struct{ int }{420}
+	allowedCtxKey = struct{ int }{405}
+)
 
 func RouteFromRequest(r *http.Request) *Route {
 	rn, _ := r.Context().Value(routeCtxKey).(*Route)
 	return rn
 }
+
+// AllowedFromRequest returns the methods allowed for the request's path,
+// it is only set when the router is responding with 405 Method Not Allowed.
+func AllowedFromRequest(r *http.Request) []string {
+	v, _ := r.Context().Value(allowedCtxKey).([]string)
+	return v
+}
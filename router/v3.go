@@ -2,7 +2,10 @@ package router
 
 import (
 	"context"
+	"fmt"
+	"maps"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -19,34 +22,54 @@ type Options struct {
 
 	NoAutoCleanURL           bool // don't automatically clean URLs, not recommended
 	NoDefaultPanicHandler    bool // don't use the default panic handler
-	NoPanicOnInvalidAddRoute bool // don't panic on invalid routes, return an error instead
+	NoPanicOnInvalidAddRoute bool // don't panic on invalid or conflicting routes, AddRoute returns nil instead, see AddRouteE
 	CatchPanics              bool // don't catch panics
 	NoAutoHeadToGet          bool // disable automatically handling HEAD requests
 	ProfileLabels            bool
 	AutoGenerateSwagger      bool
+
+	// AutoOptions answers OPTIONS requests on any known path that doesn't have its own OPTIONS route,
+	// with an Allow header computed from the registered routes.
+	AutoOptions bool
+
+	// CORS is an optional policy used by AutoOptions to answer preflight requests.
+	CORS *CORS
+
+	// RedirectTrailingSlash redirects a path with a trailing slash to the same path without it
+	// if only the latter has a route, ex: /users/ -> /users.
+	RedirectTrailingSlash bool
+
+	// RedirectCaseInsensitive redirects a path to the route it matches when ignoring the case
+	// of the route's static parts, ex: /Users/42 -> /users/42, param values are kept as is.
+	RedirectCaseInsensitive bool
+
+	// MaxValidateBodySize is the max size of the bodies Route.Validate reads, larger ones are reported
+	// with status 413 instead, it defaults to DefaultMaxValidateBodySize.
+	MaxValidateBodySize int64
 }
 
-const (
-	// tooManyStars is returned if there are multiple *params in the path
-	tooManyStars = "too many stars"
-	// starNotLast is returned if *param is not the last part of the path.
-	starNotLast = "star param must be the last part of the path"
-)
+// DefaultMaxValidateBodySize is the default of Options.MaxValidateBodySize.
+const DefaultMaxValidateBodySize = 10 << 20
+
+// MethodAny can be used as the method of a route to match requests of any method that doesn't have its own route for the path,
+// ex: a mounted http.Handler, see Router.Mount.
+const MethodAny = "*"
 
 type Route struct {
 	r        *Router
 	m        string
 	g        string
 	fp       string
-	h        Handler
-	parts    []nodePart
+	h        atomic.Pointer[Handler] // the handler passed to AddRoute or SetHandler
+	serve    atomic.Pointer[Handler] // h wrapped with mw, what ServeHTTP calls
+	mw       []Middleware            // guarded by Router.mux
+	meta     atomic.Pointer[map[any]any]
+	doc      atomic.Pointer[SwaggerRoute] // set by Doc and WithDoc, so Validate doesn't lock
+	segs     []segment
+	params   []string
 	disabled atomic.Bool
 }
 
-func (r *Route) hasStar() bool {
-	return len(r.parts) > 0 && r.parts[len(r.parts)-1].Type() == '*'
-}
-
 func (r *Route) Path() string {
 	return r.fp
 }
@@ -56,48 +79,112 @@ func (r *Route) Group() string {
 }
 
 func (r *Route) Handler() Handler {
-	return r.h
+	return *r.h.Load()
+}
+
+// SetHandler replaces the route's handler, the route's middleware still applies, it's safe to call while serving requests.
+func (r *Route) SetHandler(h Handler) {
+	r.r.mux.Lock()
+	defer r.r.mux.Unlock()
+	r.setHandler(h)
+}
+
+// Use adds middleware that only applies to this route, the first one added is the outermost, ex:
+//
+//	r.AddRoute("", "GET", "/admin", h).Use(auth, logging) // auth(logging(h))
+//
+// It's safe to call while serving requests.
+func (r *Route) Use(mw ...Middleware) *Route {
+	r.r.mux.Lock()
+	defer r.r.mux.Unlock()
+	r.mw = append(r.mw[:len(r.mw):len(r.mw)], mw...)
+	r.setHandler(*r.h.Load())
+	return r
+}
+
+// setHandler sets the handler and rebuilds the middleware chain, must be called with Router.mux held.
+func (r *Route) setHandler(h Handler) {
+	r.h.Store(&h)
+	for i := len(r.mw) - 1; i > -1; i-- {
+		h = r.mw[i](h)
+	}
+	r.serve.Store(&h)
+}
+
+// Router returns the router the route was added to.
+func (r *Route) Router() *Router {
+	return r.r
 }
 
+// Doc returns the route's documentation, documenting it with its path params first if it isn't yet, see WithDoc.
+func (r *Route) Doc() *SwaggerRoute {
+	r.r.mux.Lock()
+	sr := r.r.routeInfo(r.m, r.fp)
+	if sr != nil && sr.rn != r {
+		// documented by hand, ex: through Router.Swagger
+		cp := *sr
+		sr = r.r.setRouteInfo(r, &cp)
+	}
+	r.r.mux.Unlock()
+	if sr != nil {
+		return sr
+	}
+	return r.WithDoc("", true)
+}
+
+// WithDoc documents the route with desc, and its path params if genParams is set, replacing its previous documentation
+// except for its request body and responses, ex: documented by Doc().WithBodySchema.
 func (r *Route) WithDoc(desc string, genParams bool) *SwaggerRoute {
 	sr := &SwaggerRoute{
 		Description: desc,
 	}
 
 	if genParams {
-		for _, p := range r.parts {
-			if p[0] == ':' || p[0] == '*' {
-				sr = sr.WithParam(p.Name(), p.String()+" is required", "path", "string", true, nil)
+		for _, s := range r.segs {
+			if s.kind == segStatic {
+				continue
 			}
+			desc := s.String() + " is required"
+			if s.optional {
+				desc = s.String() + " is optional"
+			}
+			if s.cons == nil {
+				sr = sr.WithParam(s.val, desc, "path", "string", !s.optional, nil)
+				continue
+			}
+			typ, format, pattern := s.cons.schema()
+			sr = sr.WithParam(s.val, desc, "path", typ, !s.optional, &SwaggerDefinition{Format: format, Pattern: pattern})
 		}
 	}
-	r.r.addRouteInfo(r.m, r.fp, sr)
-	return sr
-}
-
-type routeMap map[string][]*Route
-
-func (rm routeMap) get(path string) []*Route {
-	return rm[path]
-}
-
-func (rm routeMap) append(path string, n *Route) {
-	rm[path] = append(rm[path], n)
+	return r.r.addRouteInfo(r, sr)
 }
 
 // Router is an efficient routing library
 type Router struct {
-	methods [9]routeMap
+	tbl     atomic.Pointer[table] // the current routes, replaced as a whole on every change
+	mux     sync.Mutex            // serializes changes to tbl and swagger
 	swagger Swagger
+	schemas atomic.Pointer[map[string]*SwaggerDefinition] // a snapshot of swagger's schemas, see storeSchemas
 
 	pp sync.Pool
 
 	NotFoundHandler         Handler
 	MethodNotAllowedHandler Handler
+	OptionsHandler          Handler // called by AutoOptions after setting the headers, defaults to 204 No Content
 	PanicHandler            PanicHandler
 
 	opts      Options
-	maxParams int
+	maxParams atomic.Int32
+}
+
+// table is an immutable snapshot of the routes, lookups load the current one and never lock,
+// changes copy the table and the nodes they touch then swap it in, see Router.update.
+type table struct {
+	methods [9]*tree
+	other   map[string]*tree // non-standard methods, ex: PROPFIND, QUERY
+	any     *tree            // MethodAny routes
+	routes  []*Route
+	names   map[string]*Route // see Route.Name
 }
 
 // New returns a new Router
@@ -109,71 +196,143 @@ func New(opts *Options) *Router {
 	}
 
 	r.pp.New = func() any {
-		return &paramsWrapper{make(Params, 0, r.maxParams)}
+		return &paramsWrapper{make(Params, 0, r.maxParams.Load())}
 	}
 
 	if !r.opts.NoDefaultPanicHandler {
 		r.PanicHandler = DefaultPanicHandler
 	}
 
-	r.swagger.OpenAPI = "3.0.3"
+	r.swagger.OpenAPI = "3.1.0"
 	r.swagger.Info = r.opts.APIInfo
+	r.tbl.Store(&table{})
 	return &r
 }
 
+// GetRoutes returns all the enabled routes as [group, method, pattern].
 func (r *Router) GetRoutes() [][3]string {
-	rms := r.getAllMaps()
-	routes := make([][3]string, 0, len(rms))
-	for method, rm := range rms {
-		for p, ns := range rm {
-			base := p
-			for _, n := range ns {
-				var route strings.Builder
-				route.WriteString(base)
-				for _, np := range n.parts {
-					route.WriteString("/" + string(np))
-				}
-				routes = append(routes, [3]string{n.g, method, route.String()})
-			}
+	t := r.tbl.Load()
+	routes := make([][3]string, 0, len(t.routes))
+	for _, rn := range t.routes {
+		if rn.disabled.Load() {
+			continue
 		}
+		routes = append(routes, [3]string{rn.g, rn.m, rn.fp})
 	}
 	return routes
 }
 
 // AddRoute adds a Handler to the specific method and route.
-// Calling AddRoute after starting the http server is racy and not supported.
+// It is safe to call AddRoute while serving requests, in-flight requests keep using the routes they started with.
 func (r *Router) AddRoute(group, method, route string, h Handler) *Route {
 	return r.AddRouteWithDesc(group, method, route, h, "")
 }
 
-// AddRouteWithDesc adds a Handler to the specific method and route.
-// Calling AddRoute after starting the http server is racy and not supported.
+// AddRouteWithDesc adds a Handler to the specific method and route, it's safe to call while serving requests.
+// It panics with the error AddRouteE would return, or returns nil instead if Options.NoPanicOnInvalidAddRoute is set.
 func (r *Router) AddRouteWithDesc(group, method, route string, h Handler, desc string) *Route {
-	p, rest, num, stars := splitPathToParts(route)
-	if stars > 1 {
-		panic(tooManyStars)
+	rn, err := r.addRoute(group, method, route, h, desc)
+	if err != nil {
+		if r.opts.NoPanicOnInvalidAddRoute {
+			return nil
+		}
+		panic(err)
 	}
+	return rn
+}
 
-	if stars == 1 && rest[len(rest)-1].Type() != '*' {
-		panic(starNotLast)
-	}
+// AddRouteE is like AddRoute, but returns a *RouteError if the method or pattern is invalid,
+// or a *ConflictError if an existing route of the method matches the same paths, instead of panicking,
+// ex: to report all the problems of routes loaded from a config file.
+func (r *Router) AddRouteE(group, method, route string, h Handler) (*Route, error) {
+	return r.addRoute(group, method, route, h, "")
+}
 
-	if n := len(p) - 1; len(p) > 1 && p[n] == '/' {
+func (r *Router) addRoute(group, method, route string, h Handler, desc string) (*Route, error) {
+	p := route
+	if n := len(p) - 1; n > 0 && p[n] == '/' {
 		p = p[:n]
 	}
 
-	m := r.getMap(method, true)
-	n := &Route{r: r, fp: route, g: group, m: method, h: h, parts: rest}
-	m.append(p, n)
+	if method != MethodAny && !validMethod(method) {
+		return nil, &RouteError{Method: method, Pattern: route, Err: ErrInvalidMethod}
+	}
+
+	segs, variants, err := validatePattern(method, route, p)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Route{r: r, fp: route, g: group, m: method, segs: segs}
+	n.setHandler(h)
+	for _, s := range segs {
+		if s.kind != segStatic {
+			n.params = append(n.params, s.val)
+		}
+	}
+
+	var conflict *Route
+	r.update(func(tbl *table) bool {
+		t := tbl.getTree(method)
+		for _, segs := range variants {
+			if ex := t.insert(segs, n); ex != n {
+				// the table copy is dropped, so nothing inserted so far is kept
+				conflict = ex
+				return false
+			}
+		}
 
-	if num > r.maxParams {
-		r.maxParams = num
+		tbl.routes = append(tbl.routes, n)
+		if l := int32(len(n.params)); l > r.maxParams.Load() {
+			r.maxParams.Store(l)
+		}
+		return true
+	})
+	if conflict != nil {
+		return nil, &ConflictError{Method: method, Pattern: route, Existing: conflict}
 	}
 
 	if desc != "" && r.opts.AutoGenerateSwagger {
 		n.WithDoc(desc, r.opts.AutoGenerateSwagger)
 	}
-	return n
+	return n, nil
+}
+
+// validatePattern returns the parsed segments of p and their optional variants, or a *RouteError.
+func validatePattern(method, route, p string) (segs []segment, variants [][]segment, err error) {
+	fail := func(param string, err error) ([]segment, [][]segment, error) {
+		return nil, nil, &RouteError{Method: method, Pattern: route, Param: param, Err: err}
+	}
+
+	segs, stars, err := parsePattern(p)
+	if err != nil {
+		return fail("", fmt.Errorf("%w (%w)", ErrInvalidPattern, err))
+	}
+	if stars > 1 {
+		return fail("", ErrTooManyStars)
+	}
+
+	seen := make(map[string]struct{}, len(segs))
+	for i, s := range segs {
+		if s.kind == segStatic {
+			continue
+		}
+		if s.kind == segStar && i != len(segs)-1 {
+			return fail(s.val, ErrStarNotLast)
+		}
+		if s.val == "" {
+			return fail("", ErrEmptyParamName)
+		}
+		if _, ok := seen[s.val]; ok {
+			return fail(s.val, ErrDuplicateParamName)
+		}
+		seen[s.val] = struct{}{}
+	}
+
+	if variants, err = expandOptional(segs); err != nil {
+		return fail("", fmt.Errorf("%w (%w)", ErrInvalidPattern, err))
+	}
+	return segs, variants, nil
 }
 
 // Match matches a method and path to a handler.
@@ -188,159 +347,267 @@ func (r *Router) Match(method, path string) (rn *Route, params Params) {
 	return rr, p.Params()
 }
 
+// Allowed returns all the methods that have an enabled route matching path, in a stable order.
+// HEAD is included if GET is and NoAutoHeadToGet isn't set, OPTIONS is included if AutoOptions is set.
+func (r *Router) Allowed(path string) (out []string) {
+	has := func(method string, t *tree) bool {
+		if t == nil {
+			return false
+		}
+		rn, p := r.matchTree(t, path, false)
+		r.putParams(p)
+		return rn != nil
+	}
+
+	var (
+		tbl   = r.tbl.Load()
+		std   [len(stdMethods)]bool
+		other = make([]string, 0, len(tbl.other))
+		found bool
+	)
+
+	for i, t := range &tbl.methods {
+		std[i] = has(stdMethods[i], t)
+		found = found || std[i]
+	}
+
+	for method, t := range tbl.other {
+		if has(method, t) {
+			other = append(other, method)
+		}
+	}
+	sort.Strings(other)
+
+	if std[0] && !r.opts.NoAutoHeadToGet {
+		std[1] = true
+	}
+
+	if r.opts.AutoOptions && (found || len(other) > 0) {
+		std[7] = true
+	}
+
+	for i, ok := range std {
+		if ok {
+			out = append(out, stdMethods[i])
+		}
+	}
+	return append(out, other...)
+}
+
+// DisableRoute disables or re-enables the route registered for method with the exact pattern path, see Route,
+// it reports whether there's such a route, a path the route matches doesn't disable it. It's safe to call while serving requests.
+// A disabled route doesn't match anything, so lookups fall through to the next best route,
+// ex: disabling /users/me makes /users/:id match /users/me.
 func (r *Router) DisableRoute(method, path string, disabled bool) bool {
-	if rr, _ := r.Match(method, path); rr != nil {
-		rr.disabled.Store(disabled)
+	if rn := r.Route(method, path); rn != nil {
+		rn.disabled.Store(disabled)
 		return true
 	}
 	return false
 }
 
+// Route returns the route registered for method with the pattern path, disabled routes included, or nil.
+// path must be the exact pattern the route was added with, ex: /users/:id, use Match to find the route of a request path.
+func (r *Router) Route(method, path string) *Route {
+	return r.tbl.Load().route(method, path)
+}
+
+// Mount routes every request under prefix, of any method that doesn't have its own route for the path, to h.
+// h gets the rest of the path in the *mountPath param, ex: mounting /admin matches /admin and /admin/users/1 with mountPath="users/1".
+// It's a shortcut for AddRoute(group, MethodAny, prefix+"/*mountPath", h).
+func (r *Router) Mount(group, prefix string, h Handler) *Route {
+	return r.AddRoute(group, MethodAny, strings.TrimSuffix(prefix, "/")+"/*mountPath", h)
+}
+
+// RemoveRoute removes the route registered for method with the pattern path, see Route,
+// along with its swagger documentation, it returns the removed route or nil.
+// It's safe to call while serving requests, in-flight requests on the removed route still finish.
+func (r *Router) RemoveRoute(method, path string) (rn *Route) {
+	removed := r.update(func(tbl *table) bool {
+		if rn = tbl.route(method, path); rn == nil {
+			return false
+		}
+
+		variants, _ := expandOptional(rn.segs)
+		t := tbl.getTree(method)
+		for _, segs := range variants {
+			t.remove(segs, rn)
+		}
+
+		routes := make([]*Route, 0, len(tbl.routes))
+		for _, o := range tbl.routes {
+			if o != rn {
+				routes = append(routes, o)
+			}
+		}
+		tbl.routes = routes
+
+		tbl.names = maps.Clone(tbl.names)
+		maps.DeleteFunc(tbl.names, func(_ string, o *Route) bool { return o == rn })
+		return true
+	})
+
+	if removed {
+		r.removeRouteInfo(rn.m, rn.fp)
+	}
+	return rn
+}
+
+// update applies fn to a copy of the current table and swaps it in if fn returns true.
+// Changes are serialized, lookups never wait for them.
+func (r *Router) update(fn func(tbl *table) bool) bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	tbl := *r.tbl.Load()
+	if tbl.other != nil {
+		tbl.other = maps.Clone(tbl.other)
+	}
+	if !fn(&tbl) {
+		return false
+	}
+	r.tbl.Store(&tbl)
+	return true
+}
+
+// route returns the route registered for method with the exact pattern, disabled ones included.
+func (tbl *table) route(method, pattern string) *Route {
+	for _, rn := range tbl.routes {
+		if rn.m == method && rn.fp == pattern {
+			return rn
+		}
+	}
+	return nil
+}
+
 // match finds the best matching handler for the given method and path.
 // It is called internally by ServeHTTP and Match, but does not handle HEAD→GET
 // fallback — that logic lives in Match().
 //
-// The algorithm proceeds in four stages:
-//
-//  1. Prefix search — walk backwards from the end of the path to find the longest
-//     prefix that exists as a key in the method's route map. For example, given
-//     "/a/b/c", it checks "/a/b/c" → "/a/b" → "/a" → "". If no prefix matches
-//     but "/" is a key, it falls back to that.
+// The lookup walks the method's radix tree (see node.find), at every level static
+// children are tried first, then :params, then *stars, backtracking on a dead end,
+// so /users/me always beats /users/:id and /users/:id/posts beats /users/*rest
+// no matter which one was registered first.
 //
-//  2. Candidate matching — for each route under the matched prefix, determine which
-//     handler applies:
-//     • Wildcard routes (*name) always win within their group.
-//     • Exact-match routes require the number of remaining segments to equal the
-//     route's part count, with every literal segment matching verbatim.
-//     • Parameterized routes (:name) only compare the non-param segments literally;
-//     param segments are skipped during comparison.
+// Param values are collected into a pool-allocated wrapper, which is only taken from
+// the pool once the walk reaches a param, and named after the matched route's params.
 //
-//  3. Parameter extraction — if a route has param or wildcard parts, extract the
-//     corresponding URL segments into Params, reusing a pool-allocated wrapper.
-//
-//  4. Return — the matched Route and its extracted Params, or nil if no route matched.
+// Disabled routes are skipped as if they didn't exist.
 func (r *Router) match(method, path string) (rn *Route, params *paramsWrapper) {
-	m := r.getMap(method, false)
-	var (
-		nn   []*Route
-		nsep int
-	)
-
-	if !revSplitPathFn(path, '/', func(p string, pidx, idx int) bool {
-		if nn = m.get(path[:idx]); nn != nil {
-			path, nsep = path[idx:], pidx
-			return true
-		}
+	tbl := r.tbl.Load()
+	if rn, params = r.matchTree(tbl.tree(method), path, false); rn == nil && tbl.any != nil {
+		rn, params = r.matchTree(tbl.any, path, false)
+	}
+	return rn, params
+}
 
-		return false
-	}) {
-		if nn = m.get("/"); nn != nil {
-			nsep = strings.Count(path, "/")
-		} else {
-			return rn, params
-		}
+// matchTree is match on a specific tree, if all is true, disabled routes can match too.
+func (r *Router) matchTree(t *tree, path string, all bool) (rn *Route, params *paramsWrapper) {
+	if t == nil {
+		return rn, params
 	}
 
-	for _, n := range nn {
-		if n.hasStar() {
-			rn = n
-			break
-		}
-		if len(n.parts) == nsep {
-			if splitPathFn(path, '/', func(p string, pidx, idx int) bool {
-				np := n.parts[pidx]
-				if np.Type() == ':' {
-					return false
-				}
-				if string(np) != p {
-					return true
-				}
+	// an exact static hit always wins, no need to walk the tree
+	if rn = t.static[path]; rn != nil && (all || !rn.disabled.Load()) {
+		return rn, params
+	}
 
-				return false
-			}) {
-				continue
-			}
-			rn = n
-			break
-		}
+	m := matcher{r: r, all: all}
+	if rn = t.root.find(path, &m); rn == nil || len(rn.params) == 0 {
+		r.putParams(m.ps)
+		return rn, nil
 	}
 
-	if rn == nil || len(rn.parts) == 0 {
-		return rn, params
+	// routes with optional params can match with fewer values than names
+	for i := range m.ps.p {
+		m.ps.p[i].Name = rn.params[i]
 	}
 
-	params = r.getParams()
-	splitPathFn(path, '/', func(p string, pidx, idx int) bool {
-		np := rn.parts[pidx]
-		switch np.Type() {
-		case ':':
-			params.p = append(params.p, Param{np.Name(), p[1:]})
-		case '*':
-			params.p = append(params.p, Param{np.Name(), path[1:]})
-			return true
-		}
-		return false
-	})
+	return rn, m.ps
+}
 
-	return rn, params
+// tree returns the route tree for method or nil, the standard methods live in a fixed array for the fast path,
+// anything else goes in a map.
+func (tbl *table) tree(method string) *tree {
+	if i := methodIndex(method); i > -1 {
+		return tbl.methods[i]
+	}
+	if method == MethodAny {
+		return tbl.any
+	}
+	return tbl.other[method]
 }
 
-func (r *Router) getAllMaps() map[string]routeMap {
-	out := make(map[string]routeMap)
-	for i, rm := range &r.methods {
-		switch i {
-		case 0:
-			out[http.MethodGet] = rm
-		case 1:
-			out[http.MethodHead] = rm
-		case 2:
-			out[http.MethodPost] = rm
-		case 3:
-			out[http.MethodPut] = rm
-		case 4:
-			out[http.MethodPatch] = rm
-		case 5:
-			out[http.MethodDelete] = rm
-		case 6:
-			out[http.MethodConnect] = rm
-		case 7:
-			out[http.MethodOptions] = rm
-		case 8:
-			out[http.MethodTrace] = rm
-		}
+// getTree returns a copy of the route tree for method that can be changed, creating it if needed.
+func (tbl *table) getTree(method string) *tree {
+	t := tbl.tree(method).clone()
+	if i := methodIndex(method); i > -1 {
+		tbl.methods[i] = t
+		return t
 	}
-	return out
+
+	if method == MethodAny {
+		tbl.any = t
+		return t
+	}
+
+	if tbl.other == nil {
+		tbl.other = map[string]*tree{}
+	}
+	tbl.other[method] = t
+	return t
 }
 
-func (r *Router) getMap(method string, create bool) routeMap {
-	var rm *routeMap
+// stdMethods are the methods with a fixed slot in Router.methods, in methodIndex order.
+var stdMethods = [...]string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+func methodIndex(method string) int {
 	switch method {
 	case http.MethodGet:
-		rm = &r.methods[0]
+		return 0
 	case http.MethodHead:
-		rm = &r.methods[1]
+		return 1
 	case http.MethodPost:
-		rm = &r.methods[2]
+		return 2
 	case http.MethodPut:
-		rm = &r.methods[3]
+		return 3
 	case http.MethodPatch:
-		rm = &r.methods[4]
+		return 4
 	case http.MethodDelete:
-		rm = &r.methods[5]
+		return 5
 	case http.MethodConnect:
-		rm = &r.methods[6]
+		return 6
 	case http.MethodOptions:
-		rm = &r.methods[7]
+		return 7
 	case http.MethodTrace:
-		rm = &r.methods[8]
+		return 8
 	default:
-		return nil
+		return -1
 	}
-	if create && *rm == nil {
-		*rm = routeMap{}
+}
+
+// validMethod reports whether method is a valid token as defined by RFC 9110 section 9.1.
+func validMethod(method string) bool {
+	if method == "" {
+		return false
 	}
+	for i := 0; i < len(method); i++ {
+		if !isTokenChar(method[i]) {
+			return false
+		}
+	}
+	return true
+}
 
-	return *rm
+func isTokenChar(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) > -1
 }
 
 func (r *Router) getParams() *paramsWrapper {
@@ -349,7 +616,7 @@ func (r *Router) getParams() *paramsWrapper {
 }
 
 func (r *Router) putParams(p *paramsWrapper) {
-	if p == nil || cap(p.p) != r.maxParams {
+	if p == nil || cap(p.p) != int(r.maxParams.Load()) {
 		return
 	}
 	p.p = p.p[:0]
@@ -0,0 +1,394 @@
+package router
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationError describes a request value that doesn't match the route's documentation, see Route.Validate.
+type ValidationError struct {
+	In      string `json:"in"`    // path, query, header, cookie or body
+	Field   string `json:"field"` // the param name, or the path of a body field, ex: items[0].name
+	Message string `json:"message"`
+	Status  int    `json:"status,omitempty"` // the HTTP status to respond with, 0 is 400 Bad Request
+}
+
+func (e ValidationError) Error() string {
+	if e.Field == "" {
+		return e.In + " " + e.Message
+	}
+	return e.In + " " + e.Field + " " + e.Message
+}
+
+// Validate checks the params, query, headers, cookies and JSON body of req against the route's documentation, see Route.Doc,
+// with the required, type, enum, minimum/maximum, minLength/maxLength, minItems/maxItems and pattern keywords of their schemas.
+// The body is read and replaced so the handler can still read it, bodies larger than Options.MaxValidateBodySize
+// aren't validated and are reported with status 413, it returns nil if the route isn't documented.
+// Like lookups, it never locks, it uses a snapshot of the route's documentation, which the SwaggerRoute builders replace
+// with an updated copy, and of the document's schemas taken when a route is documented or by Router.SchemaOf.
+func (r *Route) Validate(req *http.Request, p Params) []ValidationError {
+	sr := r.doc.Load()
+	if sr == nil {
+		return nil
+	}
+
+	v := validator{maxBody: cmp.Or(r.r.opts.MaxValidateBodySize, DefaultMaxValidateBodySize)}
+	if s := r.r.schemas.Load(); s != nil {
+		v.schemas = *s
+	}
+
+	var query map[string][]string
+	for _, param := range sr.Parameters {
+		var vals []string
+		switch param.In {
+		case "path":
+			if pv := p.Get(param.Name); pv != "" {
+				vals = []string{pv}
+			}
+		case "query":
+			if query == nil {
+				query = req.URL.Query()
+			}
+			vals = query[param.Name]
+		case "header":
+			vals = req.Header.Values(param.Name)
+		case "cookie":
+			if c, err := req.Cookie(param.Name); err == nil {
+				vals = []string{c.Value}
+			}
+		}
+		v.param(param, vals)
+	}
+
+	if sr.RequestBody != nil && req.Body != nil {
+		v.body(sr.RequestBody, req)
+	}
+	return v.errs
+}
+
+type validator struct {
+	schemas map[string]*SwaggerDefinition
+	errs    []ValidationError
+	in      string
+	maxBody int64
+}
+
+func (v *validator) fail(field, format string, args ...any) {
+	v.errs = append(v.errs, ValidationError{In: v.in, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) param(p *SwaggerParam, vals []string) {
+	v.in = p.In
+	if len(vals) == 0 {
+		// path params are only missing if they're optional
+		if p.Required && p.In != "path" {
+			v.fail(p.Name, "is required")
+		}
+		return
+	}
+
+	s := v.resolve(p.Schema)
+	if s == nil {
+		return
+	}
+	if s.Type != "array" {
+		v.value(s, parseParam(s, vals[0]), p.Name)
+		return
+	}
+
+	items := make([]any, 0, len(vals))
+	for _, val := range vals {
+		items = append(items, parseParam(v.resolve(asSchema(s.Items)), val))
+	}
+	v.value(s, items, p.Name)
+}
+
+// parseParam converts a param to the JSON value of its schema's type, or returns it as is for value to report.
+func parseParam(s *SwaggerDefinition, val string) any {
+	if s == nil {
+		return val
+	}
+	switch s.Type {
+	case "integer":
+		if isInteger(val) {
+			return json.Number(val)
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(val, 64); err == nil {
+			return json.Number(val)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+	return val
+}
+
+func (v *validator) body(rb *SwaggerRequestBody, req *http.Request) {
+	v.in = "body"
+	ct, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	c := rb.Content[ct]
+	if c == nil && ct == "" {
+		c = rb.Content["application/json"]
+	}
+	if c == nil || c.Schema == nil || !(ct == "" || ct == "application/json" || strings.HasSuffix(ct, "+json")) {
+		return
+	}
+
+	// like http.MaxBytesReader, but the part that was read is put back so the handler still gets the whole body
+	orig := req.Body
+	b, err := io.ReadAll(io.LimitReader(orig, v.maxBody+1))
+	if int64(len(b)) > v.maxBody {
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(b), orig), orig}
+		v.errs = append(v.errs, ValidationError{
+			In: v.in, Message: fmt.Sprintf("is larger than %d bytes", v.maxBody), Status: http.StatusRequestEntityTooLarge,
+		})
+		return
+	}
+	orig.Close()
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		v.fail("", "couldn't be read: %v", err)
+		return
+	}
+
+	if len(bytes.TrimSpace(b)) == 0 {
+		if rb.Required {
+			v.fail("", "is required")
+		}
+		return
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var val any
+	if err := dec.Decode(&val); err != nil {
+		v.fail("", "is invalid JSON: %v", err)
+		return
+	}
+	v.value(c.Schema, val, "")
+}
+
+// resolve follows s's $ref, if any.
+func (v *validator) resolve(s *SwaggerDefinition) *SwaggerDefinition {
+	for i := 0; s != nil && s.Ref != "" && i < 32; i++ {
+		name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		if !ok {
+			name, _ = strings.CutPrefix(s.Ref, "#/definitions/")
+		}
+		s = v.schemas[name]
+	}
+	return s
+}
+
+func asSchema(v any) *SwaggerDefinition {
+	s, _ := v.(*SwaggerDefinition)
+	return s
+}
+
+func fieldPath(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+// value validates val, a decoded JSON value, against s, null is accepted for any type like encoding/json does.
+func (v *validator) value(s *SwaggerDefinition, val any, field string) {
+	if s = v.resolve(s); s == nil || val == nil {
+		return
+	}
+
+	for _, sub := range s.AllOf {
+		v.value(sub, val, field)
+	}
+
+	if !v.typeOK(s, val, field) {
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		if !slices.ContainsFunc(s.Enum, func(e any) bool { return jsonEqual(e, val) }) {
+			v.fail(field, "must be one of %v", s.Enum)
+		}
+	}
+
+	switch val := val.(type) {
+	case json.Number:
+		f, _ := val.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			v.fail(field, "must be >= %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			v.fail(field, "must be <= %v", *s.Maximum)
+		}
+
+	case string:
+		n := utf8.RuneCountInString(val)
+		if s.MinLength != nil && n < *s.MinLength {
+			v.fail(field, "must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			v.fail(field, "must be at most %d characters", *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			if re, err := compilePattern(s.Pattern); err == nil && !re.MatchString(val) {
+				v.fail(field, "must match %s", s.Pattern)
+			}
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, val); err != nil {
+				v.fail(field, "must be an RFC 3339 date-time")
+			}
+		}
+
+	case []any:
+		if s.MinItems != nil && len(val) < *s.MinItems {
+			v.fail(field, "must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(val) > *s.MaxItems {
+			v.fail(field, "must have at most %d items", *s.MaxItems)
+		}
+		if items := asSchema(s.Items); items != nil {
+			for i, it := range val {
+				v.value(items, it, field+"["+strconv.Itoa(i)+"]")
+			}
+		}
+
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				v.fail(fieldPath(field, name), "is required")
+			}
+		}
+		props, _ := s.Properties.(map[string]*SwaggerDefinition)
+		extra := asSchema(s.AdditionalProperties)
+		for _, name := range slices.Sorted(maps.Keys(val)) {
+			fv := val[name]
+			if ps := props[name]; ps != nil {
+				v.value(ps, fv, fieldPath(field, name))
+			} else if extra != nil {
+				v.value(extra, fv, fieldPath(field, name))
+			}
+		}
+	}
+}
+
+func (v *validator) typeOK(s *SwaggerDefinition, val any, field string) bool {
+	ok := true
+	switch s.Type {
+	case "":
+		return true
+	case "object":
+		_, ok = val.(map[string]any)
+	case "array":
+		_, ok = val.([]any)
+	case "string":
+		_, ok = val.(string)
+	case "boolean":
+		_, ok = val.(bool)
+	case "number":
+		_, ok = val.(json.Number)
+	case "integer":
+		var n json.Number
+		if n, ok = val.(json.Number); ok {
+			ok = isInteger(string(n))
+		}
+	}
+
+	if !ok {
+		article := "a"
+		if s.Type == "object" || s.Type == "array" || s.Type == "integer" {
+			article = "an"
+		}
+		v.fail(field, "must be %s %s", article, s.Type)
+	}
+	return ok
+}
+
+// isInteger reports whether the JSON number n is an integer, like JSON Schema, numbers with a zero fraction are, ex: 1.0 or 1e3.
+func isInteger(n string) bool {
+	f, err := strconv.ParseFloat(n, 64)
+	return err == nil && !math.IsInf(f, 0) && f == math.Trunc(f)
+}
+
+// jsonEqual reports whether the enum value e equals val, a decoded JSON value, numbers are compared by value
+// and other values by their JSON type, ex: "1" doesn't equal 1.
+func jsonEqual(e, val any) bool {
+	if en, ok := jsonNumber(e); ok {
+		vn, ok := jsonNumber(val)
+		return ok && en == vn
+	}
+	if _, ok := jsonNumber(val); ok {
+		return false
+	}
+
+	switch ev := reflect.ValueOf(e); ev.Kind() {
+	case reflect.Invalid:
+		return val == nil
+	case reflect.String:
+		s, ok := val.(string)
+		return ok && s == ev.String()
+	case reflect.Bool:
+		b, ok := val.(bool)
+		return ok && b == ev.Bool()
+	}
+
+	// arrays and objects
+	a, err := json.Marshal(e)
+	if err != nil {
+		return false
+	}
+	b, err := json.Marshal(val)
+	return err == nil && bytes.Equal(a, b)
+}
+
+// jsonNumber returns the value of v if it's a number, including a json.Number.
+func jsonNumber(v any) (float64, bool) {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+var patterns sync.Map // pattern -> *regexp.Regexp
+
+func compilePattern(p string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(p); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(p, re)
+	return re, nil
+}
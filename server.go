@@ -65,17 +65,25 @@ func NewWithOpts(opts *Options) *Server {
 		srv.opts = *opts
 	}
 
-	ro := srv.opts.RouterOptions
-	srv.r = router.New(ro)
+	srv.r = srv.newRouter()
 
 	if srv.opts.CatchPanics {
 		srv.PanicHandler = DefaultPanicHandler
 	}
 
-	srv.r.NotFoundHandler = func(w http.ResponseWriter, req *http.Request, p router.Params) {
-		if h := srv.NotFoundHandler; h != nil {
-			ctx := getCtx(w, req, p, srv)
-			srv.NotFoundHandler(ctx)
+	srv.s, srv.Group.r = srv, srv.r
+
+	return srv
+}
+
+// newRouter returns a router using the server's options and not found/method not allowed handlers.
+func (s *Server) newRouter() *router.Router {
+	r := router.New(s.opts.RouterOptions)
+
+	r.NotFoundHandler = func(w http.ResponseWriter, req *http.Request, p router.Params) {
+		if h := s.NotFoundHandler; h != nil {
+			ctx := getCtx(w, req, p, s)
+			s.NotFoundHandler(ctx)
 			putCtx(ctx)
 			return
 		}
@@ -86,9 +94,21 @@ func NewWithOpts(opts *Options) *Server {
 		})
 	}
 
-	srv.s = srv
+	r.MethodNotAllowedHandler = func(w http.ResponseWriter, req *http.Request, p router.Params) {
+		if h := s.MethodNotAllowedHandler; h != nil {
+			ctx := getCtx(w, req, p, s)
+			s.MethodNotAllowedHandler(ctx)
+			putCtx(ctx)
+			return
+		}
 
-	return srv
+		_ = RespMethodNotAllowed.WriteToCtx(&Context{
+			Req:            req,
+			ResponseWriter: w,
+		})
+	}
+
+	return r
 }
 
 type (
@@ -103,6 +123,15 @@ type Server struct {
 	PanicHandler
 	NotFoundHandler func(ctx *Context)
 
+	// MethodNotAllowedHandler is called when the path matches a route under a different method,
+	// the Allow header is already set and ctx.AllowedMethods() returns the same list.
+	MethodNotAllowedHandler func(ctx *Context)
+
+	hosts    atomic.Pointer[map[string]*Group] // see Host, copy-on-write under hostsMux
+	hostsMux sync.Mutex
+	ach      *AutoCertHosts
+	mounted  atomic.Bool // see Group.Mount
+
 	servers    []*http.Server
 	opts       Options
 	serversMux sync.Mutex
@@ -113,15 +142,19 @@ type Server struct {
 
 // ServeHTTP implements http.Handler, allowing the server to be used in custom scenarios.
 func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	s.r.ServeHTTP(w, req)
+	if s.hosts.Load() == nil {
+		s.r.ServeHTTP(w, req)
+		return
+	}
+	s.hostRouter(req.Host).ServeHTTP(w, req)
 }
 
 func (s *Server) newHTTPServer(ctx context.Context, addr string, forceHTTP2 bool) *http.Server {
 	opts := &s.opts
 
-	h := http.Handler(s.r)
+	h := http.Handler(s)
 	if forceHTTP2 {
-		h = h2c.NewHandler(s.r, &http2.Server{})
+		h = h2c.NewHandler(s, &http2.Server{})
 	}
 
 	lg := opts.Logger
@@ -226,6 +259,7 @@ func (s *Server) logfStack(n int, f string, args ...any) {
 }
 
 // AllowCORS adds an OPTIONS route for CORS support using the given allowed methods.
+// See SetAutoOptions for answering preflight requests on every route without wiring each path.
 func (s *Server) AllowCORS(path string, allowedMethods ...string) {
 	s.AddRoute(http.MethodOptions, path, AllowCORS(allowedMethods, nil, nil))
 }
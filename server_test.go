@@ -2,13 +2,21 @@ package gserv
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -346,3 +354,1105 @@ func TestServerClose(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
+
+func TestMethodNotAllowed(t *testing.T) {
+	srv := New(setErrLogger)
+	srv.POST("/users", func(ctx *Context) Response { return RespOK })
+	srv.DELETE("/users", func(ctx *Context) Response { return RespOK })
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if got := w.Header().Get("Allow"); got != "POST, DELETE" {
+		t.Fatalf("unexpected Allow header: %q", got)
+	}
+
+	srv.MethodNotAllowedHandler = func(ctx *Context) {
+		_ = NewJSONErrorResponse(http.StatusMethodNotAllowed, strings.Join(ctx.AllowedMethods(), "|")).WriteToCtx(ctx)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/users", nil))
+	if w.Code != http.StatusMethodNotAllowed || !strings.Contains(w.Body.String(), "POST|DELETE") {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestAutoOptions(t *testing.T) {
+	srv := New(setErrLogger, SetAutoOptions(&router.CORS{Origins: []string{"https://app.example.com"}}))
+	api := srv.SubGroup("api", "/api")
+	api.GET("/users/:id", func(ctx *Context) Response { return RespOK })
+	api.DELETE("/users/:id", func(ctx *Context) Response { return RespOK })
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/users/1", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected %d, got %d", http.StatusNoContent, w.Code)
+	}
+
+	h := w.Header()
+	if got := h.Get("Allow"); got != "GET, HEAD, DELETE, OPTIONS" {
+		t.Fatalf("unexpected Allow header: %q", got)
+	}
+	if got := h.Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("unexpected Access-Control-Allow-Origin header: %q", got)
+	}
+}
+
+func TestHostRouting(t *testing.T) {
+	srv := New(setErrLogger)
+	srv.GET("/", func(ctx *Context) Response { return NewJSONResponse("default") })
+	srv.Host("api.example.com").GET("/", func(ctx *Context) Response { return NewJSONResponse("api") })
+	srv.Host("*.example.com").GET("/", func(ctx *Context) Response { return NewJSONResponse("wildcard") })
+	srv.Host("*.eu.example.com").GET("/", func(ctx *Context) Response { return NewJSONResponse("eu") })
+
+	if srv.Host("API.example.com.") != srv.Host("api.example.com") {
+		t.Fatal("expected the same group for the same host")
+	}
+
+	for host, exp := range map[string]string{
+		"api.example.com":      "api",
+		"API.example.com:8080": "api",
+		"www.example.com":      "wildcard",
+		"a.b.example.com":      "default",
+		"a.fr.eu.example.com":  "default",
+		"fr.eu.example.com":    "eu",
+		"example.com":          "default",
+		"other.com":            "default",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if !strings.Contains(w.Body.String(), `"`+exp+`"`) {
+			t.Errorf("%s: expected %s, got %d %s", host, exp, w.Code, w.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/nope", nil)
+	req.Host = "api.example.com"
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	ach := srv.AutoCertHosts()
+	srv.Host("static.example.org")
+	for host, exp := range map[string]bool{
+		"api.example.com":    true,
+		"www.example.com":    true,
+		"static.example.org": true,
+		"example.com":        false,
+		"a.b.example.com":    false,
+		"other.com":          false,
+	} {
+		if got := ach.Contains(host); got != exp {
+			t.Errorf("%s: expected %v, got %v", host, exp, got)
+		}
+	}
+}
+
+func TestHostWhileServing(t *testing.T) {
+	srv := New(setErrLogger)
+	srv.GET("/", func(ctx *Context) Response { return NewJSONResponse("default") })
+	ach := srv.AutoCertHosts()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 100 {
+			host := fmt.Sprintf("h%d.example.com", i)
+			srv.Host(host).GET("/", func(ctx *Context) Response { return NewJSONResponse(host) })
+		}
+	}()
+
+	for i := 0; ; i++ {
+		select {
+		case <-done:
+		default:
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = fmt.Sprintf("h%d.example.com", i%100)
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			if w.Code != http.StatusOK && w.Code != http.StatusNotFound {
+				t.Fatalf("%s: unexpected %d", req.Host, w.Code)
+			}
+			_ = srv.Hosts()
+			continue
+		}
+		break
+	}
+
+	if n := len(srv.Hosts()); n != 100 {
+		t.Fatalf("expected 100 hosts, got %d", n)
+	}
+	if !ach.Contains("h99.example.com") {
+		t.Fatal("expected h99.example.com to be allowed")
+	}
+}
+
+func TestRemoveAndReplaceRoute(t *testing.T) {
+	srv := New(setErrLogger)
+	api := srv.SubGroup("api", "/api")
+	api.GET("/users/me", func(ctx *Context) Response { return NewJSONResponse("me") })
+	api.GET("/users/:id", func(ctx *Context) Response { return NewJSONResponse("id") })
+
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Body.String()
+	}
+
+	if api.SetHandler(http.MethodGet, "/users/me", func(ctx *Context) Response { return NewJSONResponse("replaced") }) == nil {
+		t.Fatal("expected a route")
+	}
+	if got := get("/api/users/me"); !strings.Contains(got, `"replaced"`) {
+		t.Fatalf("unexpected response: %s", got)
+	}
+
+	if rn := api.RemoveRoute(http.MethodGet, "/users/me"); rn == nil || rn.Path() != "/api/users/me" {
+		t.Fatalf("unexpected removed route: %v", rn)
+	}
+	if got := get("/api/users/me"); !strings.Contains(got, `"id"`) {
+		t.Fatalf("unexpected response: %s", got)
+	}
+	if routes := srv.Routes(); len(routes) != 1 {
+		t.Fatalf("unexpected routes: %v", routes)
+	}
+	if api.SetHandler(http.MethodGet, "/nope") != nil || api.RemoveRoute(http.MethodGet, "/nope") != nil {
+		t.Fatal("expected nil for a missing route")
+	}
+}
+
+func TestNamedRoutes(t *testing.T) {
+	srv := New(setErrLogger)
+	users := srv.SubGroup("users", "/users")
+	users.GET("/:id", func(ctx *Context) Response { return NewJSONResponse(ctx.Param("id")) }).Name("user.show")
+	users.GET("/me", func(ctx *Context) Response {
+		return ctx.RedirectToRoute("user.show", false, "id", "42")
+	})
+	users.GET("/broken", func(ctx *Context) Response {
+		return ctx.RedirectToRoute("user.show", false)
+	})
+	srv.Host("api.example.com").GET("/v1/users/:id", nil).Name("api.user")
+
+	if u, err := srv.URL("user.show", "id", "a b"); err != nil || u != "/users/a%20b" {
+		t.Fatalf("unexpected url: %q %v", u, err)
+	}
+	if _, err := srv.URL("user.show", "id", "a/b"); !errors.Is(err, router.ErrInvalidParam) {
+		t.Fatalf("expected ErrInvalidParam, got %v", err)
+	}
+	if u, err := srv.URL("api.user", "id", "1"); err != nil || u != "/v1/users/1" {
+		t.Fatalf("unexpected url: %q %v", u, err)
+	}
+	if _, err := srv.URL("nope"); !errors.Is(err, router.ErrUnknownRoute) {
+		t.Fatalf("expected ErrUnknownRoute, got %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me", nil))
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/users/42" {
+		t.Fatalf("unexpected response: %d %v", w.Code, w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/broken", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestRouteMiddlewareAndMeta(t *testing.T) {
+	type scopes []string
+
+	requireScope := func(ctx *Context) Response {
+		s, _ := router.Meta[scopes](ctx.Route())
+		if len(s) == 0 || s[0] != ctx.ReqHeader("X-Scope") {
+			return RespForbidden
+		}
+		ctx.Set("scope", s[0])
+		return nil
+	}
+
+	srv := New(setErrLogger)
+	srv.Use(func(ctx *Context) Response {
+		ctx.Header().Set("X-Group", "1")
+		return nil
+	})
+
+	rn := srv.DELETE("/users/:id", func(ctx *Context) Response {
+		return NewJSONResponse(ctx.Get("scope"))
+	}).Use(RouteMW(requireScope))
+	router.SetMeta(rn, scopes{"users:write"})
+	srv.GET("/users/:id", func(ctx *Context) Response { return RespOK })
+
+	for _, tc := range []struct {
+		method, scope string
+		code          int
+	}{
+		{http.MethodDelete, "", http.StatusForbidden},
+		{http.MethodDelete, "users:write", http.StatusOK},
+		{http.MethodGet, "", http.StatusOK},
+	} {
+		req := httptest.NewRequest(tc.method, "/users/1", nil)
+		req.Header.Set("X-Scope", tc.scope)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if w.Code != tc.code || w.Header().Get("X-Group") != "1" {
+			t.Errorf("%s %q: unexpected response: %d %v %s", tc.method, tc.scope, w.Code, w.Header(), w.Body.String())
+		}
+		if tc.code == http.StatusOK && tc.method == http.MethodDelete && !strings.Contains(w.Body.String(), `"users:write"`) {
+			t.Errorf("unexpected body: %s", w.Body.String())
+		}
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	srv := New(setErrLogger, SetRedirects(true, true))
+	srv.GET("/users/:id", func(ctx *Context) Response { return RespOK })
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/Users/42/", nil))
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/users/42" {
+		t.Fatalf("unexpected response: %d %v", w.Code, w.Header())
+	}
+}
+
+func TestMount(t *testing.T) {
+	inner := New(setErrLogger)
+	inner.GET("/users/:id", func(ctx *Context) Response {
+		return NewJSONResponse(ctx.Param("id") + ":" + ctx.Get("user").(string) + ":" + ctx.Req.URL.Path)
+	})
+
+	srv := New(setErrLogger)
+	admin := srv.SubGroup("admin", "/admin", func(ctx *Context) Response {
+		ctx.Set("user", "root")
+		return nil
+	})
+	admin.Mount("/inner", inner)
+	admin.Mount("/std", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, req.Method+" "+req.URL.Path)
+	}))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin/inner/users/42", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("unexpected response: %d %v", w.Code, w.Header())
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(gz); !strings.Contains(string(b), `"42:root:/users/42"`) {
+		t.Fatalf("unexpected body: %s", b)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("PROPFIND", "/admin/std/a%20b/c", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "PROPFIND /a b/c" {
+		t.Fatalf("unexpected response: %d %q", w.Code, w.Body.String())
+	}
+
+	found := false
+	for _, r := range srv.Routes() {
+		found = found || r == [3]string{"admin", router.MethodAny, "/admin/inner/*mountPath"}
+	}
+	if !found {
+		t.Fatalf("mount not listed: %v", srv.Routes())
+	}
+}
+
+func listUsers(ctx *Context) Response { return nil }
+
+func TestRoutesInfo(t *testing.T) {
+	srv := New(setErrLogger)
+	api := srv.SubGroup("api", "/api", LogRequests(false))
+	api.GET("/users", listUsers).Name("users")
+	srv.DebugRoutes("/debug/routes")
+
+	var ri router.RouteInfo
+	for _, r := range srv.RoutesInfo() {
+		if r.Pattern == "/api/users" {
+			ri = r
+		}
+	}
+	exp := router.RouteInfo{
+		Method: http.MethodGet, Pattern: "/api/users", Group: "api", Name: "users",
+		Handlers: []string{"gserv.listUsers"}, Middleware: []string{"gserv.LogRequests.func1"},
+	}
+	if !reflect.DeepEqual(ri, exp) {
+		t.Fatalf("expected %+v, got %+v", exp, ri)
+	}
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/routes?format=json", nil))
+	var got []router.RouteInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || len(got) != 2 || !reflect.DeepEqual(got[0], exp) {
+		t.Fatalf("unexpected response: %v %s", err, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/routes", nil))
+	if body := w.Body.String(); !strings.Contains(body, "│   └── users   GET  api  gserv.listUsers") {
+		t.Fatalf("unexpected tree:\n%s", body)
+	}
+}
+
+func TestRouteConflict(t *testing.T) {
+	srv := New(setErrLogger)
+	srv.SubGroup("users", "/users").GET("/:id", listUsers)
+
+	defer func() {
+		var ce *router.ConflictError
+		if err, _ := recover().(error); !errors.As(err, &ce) || ce.Existing.Group() != "users" || ce.Pattern != "/users/:name" {
+			t.Fatalf("expected a conflict, got %v", err)
+		}
+	}()
+	srv.GET("/users/:name", listUsers)
+}
+
+func TestAddRouteE(t *testing.T) {
+	srv := New(setErrLogger)
+	api := srv.SubGroup("api", "/api")
+	if _, err := api.AddRouteE(http.MethodGet, "/users/:id", listUsers); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := api.AddRouteE(http.MethodGet, "/files/:id/*fp/x", listUsers)
+	var re *router.RouteError
+	if !errors.As(err, &re) || !errors.Is(err, router.ErrStarNotLast) || re.Pattern != "/api/files/:id/*fp/x" || re.Param != "fp" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err = api.AddRouteE(http.MethodGet, "/users/:name", listUsers); !errors.Is(err, router.ErrRouteConflict) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+type apiUser struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+func TestOpenAPITypes(t *testing.T) {
+	srv := New(setErrLogger)
+	JSONPost(srv, "/users", func(ctx *Context, u apiUser) (*apiUser, error) { return &u, nil }, true)
+	JSONGet(srv, "/users/:id", func(ctx *Context) ([]apiUser, error) { return nil, nil }, false).
+		WithDoc("list users", true).AsPublic()
+
+	j, err := json.Marshal(srv.Swagger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`"post":{"requestBody":{"content":{"application/json":{"schema":{"$ref":"#/components/schemas/apiUser"}}}}`,
+		`"200":{"description":"OK","content":{"application/json":{"schema":{"allOf":[{"$ref":"#/components/schemas/GenResponse_JSONCodec"},{"type":"object","properties":{"data":{"$ref":"#/components/schemas/apiUser"}}}]}}}}`,
+		`"/users/{id}":{"get":{"description":"list users","parameters":[{"name":"id"`,
+		`"200":{"description":"OK","content":{"application/json":{"schema":{"type":"array","items":{"$ref":"#/components/schemas/apiUser"}}}}}`,
+		`"default":{"description":"Error","content":{"application/json":{"schema":{"$ref":"#/components/schemas/Error"}}}}`,
+		`"apiUser":{"type":"object","properties":{"email"`,
+		`"GenResponse_JSONCodec":{"type":"object","properties"`,
+	} {
+		if !strings.Contains(string(j), s) {
+			t.Errorf("expected %s in %s", s, j)
+		}
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	srv := New(setErrLogger)
+	JSONGet(srv, "/users/:id", func(ctx *Context) (*apiUser, error) { return nil, nil }, false).WithDoc("get user", true).AsPublic()
+	JSONPost(srv, "/internal/:id", func(ctx *Context, u testUserInternal) (string, error) { return "", nil }, false)
+	srv.ServeOpenAPI("/openapi", true)
+
+	get := func(url, accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Accept", accept)
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/openapi", "")
+	var doc struct {
+		Paths      map[string]any
+		Components struct{ Schemas map[string]any }
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil || w.Header().Get("Content-Type") != MimeJSON {
+		t.Fatalf("unexpected response: %v %v %s", err, w.Header(), w.Body.String())
+	}
+	if _, ok := doc.Paths["/users/{id}"]; !ok || len(doc.Paths) != 1 || len(doc.Components.Schemas) != 3 {
+		t.Fatalf("unexpected doc: %s", w.Body.String())
+	}
+
+	for accept, ct := range map[string]string{
+		"application/yaml;q=0, application/json": MimeJSON,
+		"text/yaml;q=0.9, application/json":      MimeJSON,
+		"application/*, text/yaml":               MimeJSON,
+		"image/png":                              MimeJSON,
+		"text/html":                              "text/html; charset=utf-8",
+		"text/html;q=0.5, application/x-yaml":    MimeYAML,
+	} {
+		if w := get("/openapi", accept); w.Header().Get("Content-Type") != ct || w.Header().Get("Vary") != "Accept" {
+			t.Errorf("%s: unexpected response: %v", accept, w.Header())
+		}
+	}
+
+	w = get("/openapi", "application/json;q=0.5, text/yaml")
+	if body := w.Body.String(); w.Header().Get("Content-Type") != MimeYAML || !strings.Contains(body, `
+paths:
+  /users/{id}:
+    get:
+      description: get user
+      parameters:
+        - name: id
+          in: path
+`) || !strings.Contains(body, `
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/apiUser"
+`) {
+		t.Fatalf("unexpected yaml: %v\n%s", w.Header(), body)
+	}
+
+	if w = get("/openapi", "text/html,*/*"); !strings.Contains(w.Body.String(), "<!DOCTYPE html>") {
+		t.Fatalf("unexpected page: %v", w.Header())
+	}
+	if w = get("/openapi?format=json", "text/html"); w.Header().Get("Content-Type") != MimeJSON {
+		t.Fatalf("unexpected response: %v", w.Header())
+	}
+
+	// the middleware slice is shared, the routes must not write their handler into its spare capacity
+	mw := make([]Handler, 1, 2)
+	mw[0] = func(ctx *Context) Response { return nil }
+	srv.ServeOpenAPI("/shared/openapi", false, mw...)
+	srv.DebugRoutes("/shared/routes", mw...)
+	if w = get("/shared/openapi", ""); w.Header().Get("Content-Type") != MimeJSON || mw[:2][1] != nil {
+		t.Fatalf("unexpected response: %v %s", w.Header(), w.Body.String())
+	}
+}
+
+type testUserInternal struct {
+	Secret string `json:"secret"`
+}
+
+func TestValidateRequests(t *testing.T) {
+	srv := New(setErrLogger)
+	api := srv.SubGroup("api", "/api", ValidateRequests())
+	called := false
+	JSONPost(api, "/users/:id<int>", func(ctx *Context, u apiUser) (*apiUser, error) {
+		called = true
+		return &u, nil
+	}, true).Doc().WithParam("dry", "", "query", "boolean", false, nil)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/users/1?dry=maybe", strings.NewReader(`{"id": "x"}`)))
+	var resp JSONResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusBadRequest || called {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	exp := []Error{
+		{Message: "query dry must be a boolean", Code: http.StatusBadRequest, Field: "dry", In: "query"},
+		{Message: "body id must be an integer", Code: http.StatusBadRequest, Field: "id", In: "body"},
+	}
+	if !reflect.DeepEqual(resp.Errors, exp) {
+		t.Fatalf("expected %+v, got %+v", exp, resp.Errors)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/users/1?dry=true", strings.NewReader(`{"id": 1, "name": "bob"}`)))
+	if w.Code != http.StatusOK || !called || !strings.Contains(w.Body.String(), `"name":"bob"`) {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	// fields are only required by validate tags, like encoding/json and Validate
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/users/1", strings.NewReader(`{}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	srv = New(setErrLogger, SetRouterOptions(&router.Options{MaxValidateBodySize: 64}))
+	called = false
+	JSONPost(srv.SubGroup("api", "/api", ValidateRequests()), "/users", func(ctx *Context, u apiUser) (*apiUser, error) {
+		called = true
+		return &u, nil
+	}, true)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{"name": "`+strings.Repeat("x", 100)+`"}`)))
+	if w.Code != http.StatusRequestEntityTooLarge || called || !strings.Contains(w.Body.String(), "body is larger than 64 bytes") {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
+
+type listReq struct {
+	Org    int64         `path:"org"`
+	Page   int           `query:"page" default:"1"`
+	Tags   []string      `query:"tag"`
+	Wait   time.Duration `query:"wait" default:"5s"`
+	Since  *time.Time    `query:"since"`
+	Tenant string        `header:"X-Tenant"`
+	SID    string        `cookie:"sid"`
+}
+
+func TestBindAll(t *testing.T) {
+	srv := New(setErrLogger)
+	var got listReq
+	srv.GET("/orgs/:org", func(ctx *Context) Response {
+		got = listReq{}
+		if err := ctx.BindAll(&got); err != nil {
+			return NewJSONErrorResponse(getError(err).Status(), err)
+		}
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orgs/42?tag=a,b&tag=c&since=2024-01-02T03:04:05Z", nil)
+	req.Header.Set("X-Tenant", "acme")
+	req.AddCookie(&http.Cookie{Name: "sid", Value: "s1"})
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	exp := listReq{Org: 42, Page: 1, Tags: []string{"a", "b", "c"}, Wait: 5 * time.Second, Since: &since, Tenant: "acme", SID: "s1"}
+	if w.Code != http.StatusOK || !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected response: %d %s %+v", w.Code, w.Body.String(), got)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orgs/x?page=2&wait=soon&since=yesterday", nil))
+	var resp JSONResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	fields := make([]string, 0, len(resp.Errors))
+	for _, e := range resp.Errors {
+		fields = append(fields, e.In+" "+e.Field)
+	}
+	if exp := []string{"path org", "query wait", "query since"}; !reflect.DeepEqual(fields, exp) || got.Page != 2 {
+		t.Fatalf("expected %v, got %v (%+v)", exp, fields, resp.Errors)
+	}
+}
+
+func TestGenBindRequest(t *testing.T) {
+	type updateReq struct {
+		ID   int64  `path:"id"`
+		Dry  bool   `query:"dry"`
+		Name string `json:"name"`
+	}
+
+	srv := New(setErrLogger)
+	JSONGetReq(srv, "/orgs/:org", func(ctx *Context, req listReq) (listReq, error) { return req, nil }, false)
+	JSONPost(srv, "/users/:id", func(ctx *Context, req *updateReq) (*updateReq, error) { return req, nil }, false)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orgs/7?page=3", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"Org":7,"Page":3`) {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/9?dry=1", strings.NewReader(`{"name": "bob"}`)))
+	if w.Code != http.StatusOK || w.Body.String() != `{"ID":9,"Dry":true,"name":"bob"}`+"\n" {
+		t.Fatalf("unexpected response: %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/x", strings.NewReader(`{"name": "bob"}`)))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"id","in":"path"`) {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	j, err := json.Marshal(srv.Swagger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := string(j)
+	for _, exp := range []string{
+		`{"name":"org","in":"path","description":"{org ':'} is required","schema":{"type":"integer","format":"int64"},"required":true}`,
+		`{"name":"tag","in":"query","schema":{"type":"array","items":{"type":"string"}}}`,
+		`{"name":"wait","in":"query","schema":{"type":"string"}}`,
+		`{"name":"X-Tenant","in":"header","schema":{"type":"string"}}`,
+		`"updateReq":{"type":"object","properties":{"name":{"type":"string"}}}`,
+	} {
+		if !strings.Contains(doc, exp) {
+			t.Fatalf("expected %s in %s", exp, doc)
+		}
+	}
+	if sr := srv.Swagger().Paths["/orgs/:org"]["get"]; sr.RequestBody != nil {
+		t.Fatalf("GET requests shouldn't have a body: %s", doc)
+	}
+}
+
+type validOrg struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type validUser struct {
+	Name  string            `json:"name" validate:"required,min=2,max=8"`
+	Email string            `json:"email,omitempty" validate:"omitempty,email"`
+	Role  string            `json:"role" validate:"oneof=admin user"`
+	Age   int               `json:"age" validate:"min=18"`
+	Tags  []string          `json:"tags" validate:"max=2,dive,len=2"`
+	Org   *validOrg         `json:"org" validate:"required"`
+	Orgs  []validOrg        `json:"orgs"`
+	Meta  map[string]string `json:"meta" validate:"dive,url"`
+	Dry   bool              `query:"dry"`
+	Page  int               `query:"page" validate:"max=10"`
+}
+
+func TestValidate(t *testing.T) {
+	u := validUser{
+		Name: "bob", Email: "bob@example.com", Role: "user", Age: 20, Tags: []string{"ab"},
+		Org: &validOrg{Name: "x"}, Meta: map[string]string{"home": "https://example.com"},
+	}
+	if err := Validate(&u); err != nil {
+		t.Fatal(err)
+	}
+
+	u = validUser{
+		Name: "b", Email: "bob", Role: "root", Age: 1, Tags: []string{"a", "bc", "d"},
+		Orgs: []validOrg{{Name: "x"}, {}}, Meta: map[string]string{"home": "/x"}, Page: 11,
+	}
+	err := Validate(u)
+	var fe FieldErrors
+	if !errors.As(err, &fe) || fe.Status() != http.StatusUnprocessableEntity {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := make([]string, 0, len(fe))
+	for _, e := range fe {
+		got = append(got, e.In+" "+e.Field+" "+e.Message)
+	}
+	exp := []string{
+		"body name must be at least 2 characters",
+		"body email must be an email address",
+		"body role must be one of [admin user]",
+		"body age must be >= 18",
+		"body tags must have at most 2 items",
+		"body tags[0] must be 2 characters",
+		"body tags[2] must be 2 characters",
+		"body org is required",
+		"body orgs[1].name is required",
+		"body meta.home must be a URL",
+		"query page must be <= 10",
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Fatalf("expected:\n%s\ngot:\n%s", strings.Join(exp, "\n"), strings.Join(got, "\n"))
+	}
+
+}
+
+// foreignUser has tags of other validators, which Validate ignores.
+type foreignUser struct {
+	ID    string   `json:"id" validate:"required,uuid"`
+	Phone string   `json:"phone" validate:"omitempty,e164"`
+	Age   int      `json:"age" validate:"gte=18,lte=130,min=x"`
+	Code  string   `json:"code" validate:"alphanum,min=2|max=4"`
+	Tags  []string `json:"tags" validate:"dive,dive,required"`
+}
+
+func TestValidateForeignTags(t *testing.T) {
+	if err := Validate(foreignUser{ID: "x", Age: 1}); err != nil {
+		t.Fatal(err)
+	}
+	var fe FieldErrors
+	if err := Validate(foreignUser{}); !errors.As(err, &fe) || len(fe) != 1 || fe[0].Field != "id" || fe[0].Message != "is required" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := New(setErrLogger)
+	JSONPost(srv, "/users", func(ctx *Context, u foreignUser) (foreignUser, error) { return u, nil }, false)
+	srv.POST("/bind", func(ctx *Context) Response {
+		var u foreignUser
+		if err := ctx.BindJSON(&u); err != nil {
+			return NewJSONErrorResponse(getError(err).Status(), err)
+		}
+		return NewJSONResponse(u)
+	})
+	for _, p := range []string{"/users", "/bind"} {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, p, strings.NewReader(`{"id": "a", "age": 5}`)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected response: %d %s", p, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestValidateBind(t *testing.T) {
+	srv := New(setErrLogger)
+	JSONPost(srv, "/users", func(ctx *Context, u validUser) (*validUser, error) { return &u, nil }, true)
+	srv.POST("/bind", func(ctx *Context) Response {
+		var u validOrg
+		if err := ctx.BindJSON(&u); err != nil {
+			return NewJSONErrorResponse(getError(err).Status(), err)
+		}
+		return NewJSONResponse(u)
+	})
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users?page=20", strings.NewReader(`{"name": "bob", "role": "user", "age": 30}`)))
+	var resp JSONResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	exp := []Error{
+		{Message: "is required", Code: http.StatusUnprocessableEntity, Field: "org", In: "body"},
+		{Message: "must be <= 10", Code: http.StatusUnprocessableEntity, Field: "page", In: "query"},
+	}
+	if !reflect.DeepEqual(resp.Errors, exp) {
+		t.Fatalf("expected %+v, got %+v", exp, resp.Errors)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bind", strings.NewReader(`{}`)))
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"field":"name"`) {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	j, err := json.Marshal(srv.Swagger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{
+		`"name":{"type":"string","minLength":2,"maxLength":8}`,
+		`"role":{"type":"string","enum":["admin","user"]}`,
+		`"tags":{"type":"array","maxItems":2,"items":{"type":"string","minLength":2,"maxLength":2}}`,
+		`"meta":{"type":"object","additionalProperties":{"type":"string","format":"uri"}}`,
+		`"validUser":{"type":"object","required":["name","org"]`,
+		`{"name":"page","in":"query","schema":{"type":"integer","format":"int64","maximum":10}}`,
+	} {
+		if !strings.Contains(string(j), exp) {
+			t.Fatalf("expected %s in %s", exp, j)
+		}
+	}
+}
+
+type upperCodec struct{ PlainTextCodec }
+
+func (upperCodec) ContentType() string { return "text/x-upper" }
+
+func (upperCodec) Encode(w io.Writer, v any) error {
+	_, err := io.WriteString(w, strings.ToUpper(fmt.Sprint(v)))
+	return err
+}
+
+func TestNegotiate(t *testing.T) {
+	RegisterCodec("text/x-upper", upperCodec{})
+
+	srv := New(setErrLogger)
+	calls := 0
+	JSONGet(srv, "/wrapped", func(ctx *Context) (string, error) { calls++; return "hi", nil }, true)
+	JSONGet(srv, "/raw", func(ctx *Context) (string, error) { calls++; return "hi", nil }, false)
+	srv.GET("/err", func(ctx *Context) Response { return NewJSONErrorResponse(http.StatusNotFound) })
+	srv.GET("/encode", func(ctx *Context) Response {
+		// negotiating first doesn't list Accept twice in Vary
+		_, _ = ctx.Negotiate(JSONCodec{})
+		_ = ctx.Encode(http.StatusCreated, "hi")
+		return nil
+	})
+
+	for _, tc := range []struct {
+		path, accept, ct string
+		code             int
+	}{
+		{"/wrapped", "", MimeJSON, http.StatusOK},
+		{"/wrapped", "*/*", MimeJSON, http.StatusOK},
+		{"/wrapped", "text/html, application/*;q=0.5", MimeJSON, http.StatusOK},
+		{"/wrapped", "application/msgpack", MimeMsgPack, http.StatusOK},
+		{"/wrapped", "application/json;q=0.5, application/msgpack", MimeMsgPack, http.StatusOK},
+		{"/wrapped", "application/msgpack, application/json", MimeMsgPack, http.StatusOK},
+		{"/wrapped", "application/json, */*;q=0.1", MimeJSON, http.StatusOK},
+		{"/wrapped", "text/*, application/json;q=0.9", "text/x-upper", http.StatusOK},
+		{"/wrapped", "application/json;q=0, */*", MimeMsgPack, http.StatusOK},
+		{"/wrapped", "text/html", MimeJSON, http.StatusNotAcceptable},
+		{"/raw", "application/msgpack", MimeMsgPack, http.StatusOK},
+		{"/raw", "text/x-upper", "text/x-upper", http.StatusOK},
+		{"/raw", "image/png", MimeJSON, http.StatusNotAcceptable},
+		{"/err", "image/png", MimeJSON, http.StatusNotFound},
+		{"/encode", "application/msgpack", MimeMsgPack, http.StatusCreated},
+		{"/encode", "image/png", MimeJSON, http.StatusNotAcceptable},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		if ct := w.Header().Get("Content-Type"); w.Code != tc.code || ct != tc.ct || !slices.Equal(w.Header().Values("Vary"), []string{"Accept"}) {
+			t.Fatalf("%s %q: unexpected response: %d %s %s", tc.path, tc.accept, w.Code, ct, w.Body.String())
+		}
+		if tc.ct == "text/x-upper" && !strings.Contains(w.Body.String(), "HI") {
+			t.Fatalf("%s %q: unexpected body: %s", tc.path, tc.accept, w.Body.String())
+		}
+		if tc.path == "/encode" && tc.code == http.StatusNotAcceptable {
+			var resp JSONResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Code != tc.code || resp.Success || len(resp.Errors) != 1 {
+				t.Fatalf("%s %q: expected an error response, got %s (%v)", tc.path, tc.accept, w.Body.String(), err)
+			}
+		}
+	}
+	if calls != 11 {
+		t.Fatalf("handlers shouldn't run for 406 responses: %d calls", calls)
+	}
+}
+
+func TestCodecFor(t *testing.T) {
+	RegisterCodec("text/x-upper", upperCodec{})
+
+	for ct, exp := range map[string]Codec{
+		"application/json":                      JSONCodec{},
+		"Application/JSON; charset=utf-8":       JSONCodec{},
+		"application/problem+json":              JSONCodec{},
+		"application/vnd.x+msgpack":             MsgpCodec{},
+		"text/json":                             JSONCodec{},
+		"application/x-json":                    JSONCodec{},
+		"text/x-upper":                          upperCodec{},
+		"text/plain":                            nil,
+		"application/vnd.x+unknown":             nil,
+		"":                                      nil,
+		"application/merge-patch+json; q=weird": JSONCodec{},
+	} {
+		if c := CodecFor(ct); c != exp {
+			t.Fatalf("%q: expected %T, got %T", ct, exp, c)
+		}
+	}
+
+	srv := New(setErrLogger)
+	JSONPost(srv, "/echo", func(ctx *Context, s string) (string, error) { return s, nil }, false)
+	srv.POST("/bind", func(ctx *Context) Response {
+		var s string
+		if err := ctx.Bind(&s); err != nil {
+			return NewJSONErrorResponse(http.StatusBadRequest, err)
+		}
+		return NewJSONResponse(s)
+	})
+
+	for path, exp := range map[string]string{"/echo": `"hi there"` + "\n", "/bind": `{"data":"hi there","code":200,"success":true}` + "\n"} {
+		for ct, body := range map[string]string{"application/vnd.api+json": `"hi there"`, "text/x-upper": "hi there"} {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", ct)
+			req.Header.Set("Accept", MimeJSON)
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			if w.Code != http.StatusOK || w.Body.String() != exp {
+				t.Fatalf("%s %s: unexpected response: %d %q", path, ct, w.Code, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestXMLCodec(t *testing.T) {
+	srv := New(setErrLogger)
+	XMLGet(srv, "/users", func(ctx *Context) ([]apiUser, error) {
+		return []apiUser{{ID: 1, Name: "bob"}, {ID: 2, Name: "alice"}}, nil
+	}, true)
+	XMLPost(srv, "/users", func(ctx *Context, u apiUser) (apiUser, error) {
+		if u.Name == "" {
+			return u, NewError(http.StatusBadRequest, "missing name")
+		}
+		return u, nil
+	}, false)
+	XMLPost(srv, "/wrapped", func(ctx *Context, u apiUser) (apiUser, error) { return u, ErrNotFound }, true)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	exp := `<response><data><item><ID>1</ID><Name>bob</Name><Email></Email></item><item><ID>2</ID><Name>alice</Name><Email></Email></item></data>` +
+		`<code>200</code><success>true</success></response>` + "\n"
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != MimeXML || w.Body.String() != exp {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	var users []apiUser
+	resp := XMLResponse{Data: &users}
+	if err := xml.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[1].Name != "alice" || !resp.Success || resp.Code != http.StatusOK {
+		t.Fatalf("unexpected response: %+v %+v", resp, users)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`<apiUser><ID>3</ID><Name>eve</Name></apiUser>`)))
+	if exp := `<apiUser><ID>3</ID><Name>eve</Name><Email></Email></apiUser>` + "\n"; w.Code != http.StatusOK || w.Body.String() != exp {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`<apiUser></apiUser>`)))
+	if exp := `<Error><message>missing name</message><code>400</code></Error>` + "\n"; w.Code != http.StatusBadRequest || w.Body.String() != exp {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/wrapped", strings.NewReader(`<apiUser></apiUser>`)))
+	exp = `<response><errors><error><message>not found</message><code>404</code></error></errors><code>404</code><success>false</success></response>` + "\n"
+	if w.Code != http.StatusNotFound || w.Body.String() != exp {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	// encoding/xml can't encode maps, the error replaces the response instead of a truncated 200
+	srv.GET("/map", func(ctx *Context) Response { return NewXMLResponse(map[string]int{"a": 1}) })
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/map", nil))
+	resp = XMLResponse{}
+	if err := xml.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusInternalServerError ||
+		resp.Code != http.StatusInternalServerError || resp.Success || len(resp.Errors) != 1 {
+		t.Fatalf("unexpected response: %d %s (%v)", w.Code, w.Body.String(), err)
+	}
+}
+
+func TestCBORCodec(t *testing.T) {
+	date := time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)
+	// RFC 8949 appendix A
+	for _, tc := range []struct {
+		v   any
+		hex string
+	}{
+		{0, "00"}, {23, "17"}, {24, "1818"}, {1000, "1903e8"}, {int64(1000000000000), "1b000000e8d4a51000"},
+		{uint64(18446744073709551615), "1bffffffffffffffff"}, {-1, "20"}, {-1000, "3903e7"},
+		{0.0, "f90000"}, {1.0, "f93c00"}, {1.1, "fb3ff199999999999a"}, {65504.0, "f97bff"}, {100000.0, "fa47c35000"},
+		{3.4028234663852886e+38, "fa7f7fffff"}, {1.0e+300, "fb7e37e43c8800759c"}, {5.960464477539063e-8, "f90001"}, {-4.0, "f9c400"},
+		{false, "f4"}, {true, "f5"}, {"", "60"}, {"IETF", "6449455446"}, {"ü", "62c3bc"}, {[]byte{1, 2, 3, 4}, "4401020304"},
+		{[]int{}, "80"}, {[][]int{{1}, {2, 3}}, "828101820203"}, {map[int]int{3: 4, 1: 2}, "a201020304"},
+		{map[string]string{"e": "E", "a": "A", "c": "C"}, "a3616161416163614361656145"},
+		{date, "c074323031332d30332d32315432303a30343a30305a"},
+		{apiUser{ID: 1, Name: "bob"}, "a262696401646e616d6563626f62"},
+	} {
+		var buf bytes.Buffer
+		if err := (CBORCodec{}).Encode(&buf, tc.v); err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprintf("%x", buf.Bytes()); got != tc.hex {
+			t.Fatalf("%#v: expected %s, got %s", tc.v, tc.hex, got)
+		}
+
+		out := reflect.New(reflect.TypeOf(tc.v))
+		if err := (CBORCodec{}).Decode(&buf, out.Interface()); err != nil {
+			t.Fatalf("%#v: %v", tc.v, err)
+		}
+		if !reflect.DeepEqual(out.Elem().Interface(), tc.v) {
+			t.Fatalf("expected %#v, got %#v", tc.v, out.Elem().Interface())
+		}
+	}
+
+	for _, tc := range []struct {
+		hex string
+		out any
+		exp any
+	}{
+		{"5f42010243030405ff", new([]byte), []byte{1, 2, 3, 4, 5}},
+		{"9f018202039f0405ffff", new(any), []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+		{"bf61610161629f0203ffff", new(any), map[string]any{"a": int64(1), "b": []any{int64(2), int64(3)}}},
+		{"a201020304", new(any), map[any]any{int64(1): int64(2), int64(3): int64(4)}},
+		{"c11a514b67b0", new(time.Time), date},
+		{"f97c00", new(float32), float32(math.Inf(1))},
+		{"a3624944076446554c4c63626f62646e616d6563657665", new(apiUser), apiUser{ID: 7, Name: "eve"}},
+	} {
+		b, _ := hex.DecodeString(tc.hex)
+		if err := (CBORCodec{}).Decode(bytes.NewReader(b), tc.out); err != nil {
+			t.Fatalf("%s: %v", tc.hex, err)
+		}
+		if got := reflect.ValueOf(tc.out).Elem().Interface(); !reflect.DeepEqual(got, tc.exp) {
+			t.Fatalf("%s: expected %#v, got %#v", tc.hex, tc.exp, got)
+		}
+	}
+
+	for _, h := range []string{"", "18", "62c3", "9b00000000ffffffff", "a1", "0000", "1c"} {
+		b, _ := hex.DecodeString(h)
+		if err := (CBORCodec{}).Decode(bytes.NewReader(b), new(any)); err == nil {
+			t.Fatalf("%q: expected an error", h)
+		}
+	}
+	if err := (CBORCodec{}).Decode(bytes.NewReader([]byte{0x20}), new(uint)); err == nil {
+		t.Fatal("expected an overflow error")
+	}
+}
+
+func TestCBORRoutes(t *testing.T) {
+	srv := New(setErrLogger)
+	CBORGet(srv, "/users", func(ctx *Context) ([]apiUser, error) {
+		return []apiUser{{ID: 1, Name: "bob"}, {ID: 2, Name: "alice", Email: "alice@example.com"}}, nil
+	}, true)
+	CBORPost(srv, "/users", func(ctx *Context, u apiUser) (apiUser, error) {
+		if u.Name == "" {
+			return u, NewError(http.StatusBadRequest, "missing name")
+		}
+		return u, nil
+	}, false)
+	CBORPost(srv, "/wrapped", func(ctx *Context, u apiUser) (apiUser, error) { return u, ErrNotFound }, true)
+	JSONGet(srv, "/json", func(ctx *Context) (apiUser, error) { return apiUser{ID: 3}, nil }, false)
+
+	encode := func(v any) io.Reader {
+		var buf bytes.Buffer
+		if err := (CBORCodec{}).Encode(&buf, v); err != nil {
+			t.Fatal(err)
+		}
+		return &buf
+	}
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != MimeCBOR {
+		t.Fatalf("unexpected response: %d %x", w.Code, w.Body.Bytes())
+	}
+	var users []apiUser
+	resp := CBORResponse{Data: &users}
+	if err := (CBORCodec{}).Decode(w.Body, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[1].Email != "alice@example.com" || !resp.Success || resp.Code != http.StatusOK || resp.Errors != nil {
+		t.Fatalf("unexpected response: %+v %+v", resp, users)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users", encode(apiUser{ID: 3, Name: "eve"})))
+	var u apiUser
+	if err := (CBORCodec{}).Decode(w.Body, &u); err != nil || w.Code != http.StatusOK || u != (apiUser{ID: 3, Name: "eve"}) {
+		t.Fatalf("unexpected response: %d %+v %v", w.Code, u, err)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users", encode(apiUser{})))
+	var e Error
+	if err := (CBORCodec{}).Decode(w.Body, &e); err != nil || w.Code != http.StatusBadRequest || e.Message != "missing name" || e.Code != 400 {
+		t.Fatalf("unexpected response: %d %+v %v", w.Code, e, err)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("\xa1")))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected response: %d %x", w.Code, w.Body.Bytes())
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/wrapped", encode(apiUser{})))
+	resp = CBORResponse{}
+	if err := (CBORCodec{}).Decode(w.Body, &resp); err != nil || w.Code != http.StatusNotFound ||
+		resp.Success || len(resp.Errors) != 1 || resp.Errors[0].Message != "not found" || resp.Data != nil {
+		t.Fatalf("unexpected response: %d %+v %v", w.Code, resp, err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/json", nil)
+	req.Header.Set("Accept", "application/cbor")
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	u = apiUser{}
+	if err := (CBORCodec{}).Decode(w.Body, &u); err != nil || w.Header().Get("Content-Type") != MimeCBOR || u.ID != 3 {
+		t.Fatalf("unexpected response: %d %+v %v", w.Code, u, err)
+	}
+}
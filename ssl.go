@@ -137,7 +137,8 @@ func NewAutoCertHosts(hosts ...string) *AutoCertHosts {
 	return &ach
 }
 
-// AutoCertHosts provides a dynamic host whitelist for LetsEncrypt autocert.
+// AutoCertHosts provides a dynamic host whitelist for LetsEncrypt autocert,
+// a wildcard host (ex: *.example.com) allows a certificate for any direct subdomain of it.
 type AutoCertHosts struct {
 	m   otk.Set
 	mux sync.RWMutex
@@ -152,15 +153,23 @@ func (a *AutoCertHosts) Set(hosts ...string) {
 
 func (a *AutoCertHosts) appendHosts(hosts ...string) (m map[string]struct{}) {
 	for _, h := range hosts {
+		// a wildcard isn't a valid idna name, so only convert the part after it
+		wc := strings.HasPrefix(h, "*.")
+		if wc {
+			h = h[2:]
+		}
 		// copied from autocert.HostWhiteList
 		if h, err := idna.Lookup.ToASCII(h); err == nil {
+			if wc {
+				h = "*." + h
+			}
 			a.m.Set(h)
 		}
 	}
 	return
 }
 
-// Contains checks if the given host is in the whitelist.
+// Contains checks if the given host is in the whitelist, directly or through a wildcard.
 func (a *AutoCertHosts) Contains(host string) bool {
 	host = strings.ToLower(host)
 	if h, err := idna.Lookup.ToASCII(host); err == nil {
@@ -169,9 +178,15 @@ func (a *AutoCertHosts) Contains(host string) bool {
 		return false
 	}
 	a.mux.RLock()
-	ok := a.m.Has(host)
-	a.mux.RUnlock()
-	return ok
+	defer a.mux.RUnlock()
+	if a.m.Has(host) {
+		return true
+	}
+	// like a wildcard certificate, *.example.com only covers a single label, ex: a.example.com but not a.b.example.com
+	if i := strings.IndexByte(host, '.'); i != -1 {
+		return a.m.Has("*." + host[i+1:])
+	}
+	return false
 }
 
 // IsAllowed implements autocert.HostPolicy, returning an error if the host is not allowed.
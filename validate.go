@@ -0,0 +1,338 @@
+package gserv
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// Validate checks out, a struct or a pointer to one, against the validate tags of its fields, ex:
+//
+//	type CreateUser struct {
+//		Name  string   `json:"name" validate:"required,min=1,max=64"`
+//		Email string   `json:"email" validate:"omitempty,email"`
+//		Role  string   `json:"role" validate:"oneof=admin user"`
+//		Tags  []string `json:"tags" validate:"max=8,dive,min=2"`
+//		Org   *Org     `json:"org"` // nested structs are always validated
+//	}
+//
+// The rules are:
+//   - required: the value isn't zero or nil.
+//   - omitempty: skips the other rules if the value is zero.
+//   - min=n, max=n, len=n: the value of numbers, the length in characters of strings and the length of slices and maps.
+//   - oneof=a b c: the value is one of the space separated list.
+//   - email: an email address, ex: bob@example.com.
+//   - url: an absolute URL, ex: https://example.com/x.
+//   - dive: the rules after it apply to each element of a slice, array or map.
+//
+// Fields of nested structs, and of structs in slices and maps, are validated as well and named by their path,
+// ex: items[0].name, using their json names or the names of their path, query, header, cookie or form tags, see BindAll.
+//
+// Rules it doesn't know, ex: gte or uuid of other validators, and rules with invalid args are ignored,
+// so structs shared with other validators keep working.
+//
+// Every invalid value is returned in a FieldErrors with status 422.
+func Validate(out any) error {
+	v := reflect.ValueOf(out)
+	if !v.IsValid() {
+		return nil
+	}
+
+	var errs FieldErrors
+	validateNested(v, "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+type validateRule struct {
+	name string
+	arg  string
+	num  float64 // arg of min, max and len
+}
+
+type validateField struct {
+	index []int
+	name  string
+	in    string
+	rules []validateRule
+	dive  []validateRule
+}
+
+var validatePlans sync.Map // reflect.Type -> []validateField
+
+// validatePlanOf returns the fields of the struct type t that have rules or may contain structs.
+func validatePlanOf(t reflect.Type) []validateField {
+	if fs, ok := validatePlans.Load(t); ok {
+		return fs.([]validateField)
+	}
+	var fs []validateField
+	collectValidateFields(t, nil, &fs)
+	validatePlans.Store(t, fs)
+	return fs
+}
+
+func collectValidateFields(t reflect.Type, index []int, fs *[]validateField) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		idx := append(slices.Clip(index), i)
+
+		in, name, bound := bindTag(f)
+		if !bound {
+			jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if f.Anonymous && jsonName == "" && f.Type.Kind() == reflect.Struct {
+				collectValidateFields(f.Type, idx, fs)
+				continue
+			}
+			if jsonName == "-" {
+				continue
+			}
+			in, name = "body", jsonName
+			if name == "" {
+				name = f.Name
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := f.Tag.Get("validate")
+		if tag == "" && !mayHaveRules(f.Type) {
+			continue
+		}
+		vf := validateField{index: idx, name: name, in: in}
+		vf.rules, vf.dive = parseValidateTag(tag)
+		*fs = append(*fs, vf)
+	}
+}
+
+// elemType returns the type pointed to by t, or the element type of slices, arrays and maps of t.
+func elemType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+// mayHaveRules reports whether values of t can contain structs.
+func mayHaveRules(t reflect.Type) bool {
+	t = elemType(t)
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+// parseValidateTag returns the rules of tag before and after dive, rules it doesn't know or with invalid args are ignored.
+func parseValidateTag(tag string) (rules, dive []validateRule) {
+	inDive := false
+	for r := range strings.SplitSeq(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(r), "=")
+		vr := validateRule{name: name, arg: arg}
+		switch name {
+		case "dive":
+			if inDive { // nested dives aren't supported
+				return rules, dive
+			}
+			inDive = true
+			continue
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			vr.num = n
+		case "oneof":
+			if strings.TrimSpace(arg) == "" {
+				continue
+			}
+		case "required", "omitempty", "email", "url":
+		default:
+			continue
+		}
+		if inDive {
+			dive = append(dive, vr)
+		} else {
+			rules = append(rules, vr)
+		}
+	}
+	return rules, dive
+}
+
+// validateNested validates the fields of the structs in v.
+func validateNested(v reflect.Value, path string, errs *FieldErrors) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return
+		}
+		for _, f := range validatePlanOf(v.Type()) {
+			name := f.name
+			if f.in == "body" {
+				name = fieldPath(path, f.name)
+			}
+			validateValue(v.FieldByIndex(f.index), f.rules, f.dive, name, f.in, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		if !mayHaveRules(v.Type().Elem()) {
+			return
+		}
+		for i := range v.Len() {
+			validateNested(v.Index(i), path+"["+strconv.Itoa(i)+"]", errs)
+		}
+	case reflect.Map:
+		if !mayHaveRules(v.Type().Elem()) {
+			return
+		}
+		for it := v.MapRange(); it.Next(); {
+			validateNested(it.Value(), fieldPath(path, fmt.Sprint(it.Key())), errs)
+		}
+	}
+}
+
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func validateValue(v reflect.Value, rules, dive []validateRule, field, in string, errs *FieldErrors) {
+	has := func(name string) bool {
+		return slices.ContainsFunc(rules, func(r validateRule) bool { return r.name == name })
+	}
+	fail := func(msg string) {
+		*errs = append(*errs, Error{Code: http.StatusUnprocessableEntity, Field: field, In: in, Message: msg})
+	}
+
+	if v.IsZero() && has("omitempty") {
+		return
+	}
+
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			if has("required") {
+				fail("is required")
+			}
+			return
+		}
+		v = v.Elem()
+	}
+
+	for _, r := range rules {
+		if msg := checkRule(v, r); msg != "" {
+			fail(msg)
+			if r.name == "required" {
+				return
+			}
+		}
+	}
+
+	if len(dive) > 0 {
+		switch v.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := range v.Len() {
+				validateValue(v.Index(i), dive, nil, field+"["+strconv.Itoa(i)+"]", in, errs)
+			}
+			return
+		case reflect.Map:
+			for it := v.MapRange(); it.Next(); {
+				validateValue(it.Value(), dive, nil, fieldPath(field, fmt.Sprint(it.Key())), in, errs)
+			}
+			return
+		}
+	}
+
+	validateNested(v, field, errs)
+}
+
+// checkRule returns why v doesn't follow r, or an empty string.
+func checkRule(v reflect.Value, r validateRule) string {
+	switch r.name {
+	case "required":
+		if v.IsZero() {
+			return "is required"
+		}
+
+	case "min", "max", "len":
+		n, unit, ok := measure(v)
+		if !ok {
+			return ""
+		}
+		verb := "must be"
+		if unit == "items" {
+			verb = "must have"
+		}
+		switch {
+		case r.name == "min" && n < r.num:
+			if unit == "" {
+				return "must be >= " + r.arg
+			}
+			return verb + " at least " + r.arg + " " + unit
+		case r.name == "max" && n > r.num:
+			if unit == "" {
+				return "must be <= " + r.arg
+			}
+			return verb + " at most " + r.arg + " " + unit
+		case r.name == "len" && n != r.num:
+			return strings.TrimSpace(verb + " " + r.arg + " " + unit)
+		}
+
+	case "oneof":
+		opts := strings.Fields(r.arg)
+		if !slices.Contains(opts, fmt.Sprint(v.Interface())) {
+			return "must be one of [" + strings.Join(opts, " ") + "]"
+		}
+
+	case "email":
+		if v.Kind() == reflect.String {
+			if a, err := mail.ParseAddress(v.String()); err != nil || a.Address != v.String() {
+				return "must be an email address"
+			}
+		}
+
+	case "url":
+		if v.Kind() == reflect.String {
+			if u, err := url.Parse(v.String()); err != nil || u.Scheme == "" || u.Host == "" {
+				return "must be a URL"
+			}
+		}
+	}
+	return ""
+}
+
+// measure returns the value of numbers, or the length of strings, slices, arrays and maps with its unit.
+func measure(v reflect.Value) (n float64, unit string, ok bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), "characters", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), "items", true
+	}
+	return 0, "", false
+}